@@ -327,10 +327,8 @@ func (g *GenericAdmin[T]) getActions() []AdminAction {
 			Label:       "Delete selected " + reflect.TypeOf((*T)(nil)).Elem().Elem().Name() + "s",
 			Description: "Delete the selected objects",
 			Handler: func(qs *queryset.QuerySet[T], ids []uint64) (string, error) {
-				for _, id := range ids {
-					if err := qs.Delete(id); err != nil {
-						return "", err
-					}
+				if _, _, err := qs.Filter(queryset.Q{"id__in": ids}).Delete(); err != nil {
+					return "", err
 				}
 				return fmt.Sprintf("Successfully deleted %d items.", len(ids)), nil
 			},
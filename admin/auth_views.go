@@ -143,7 +143,7 @@ func (v *LoginView) authenticateUser(username, password string) (auth.Authentica
 	}
 
 	// Query the user
-	sql := fmt.Sprintf("SELECT * FROM %s WHERE username = $1 LIMIT 1", tableName)
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE username = %s LIMIT 1", tableName, v.DB.Ops().Placeholder(1))
 	rows, err := v.DB.Query(sql, username)
 	if err != nil {
 		return nil, fmt.Errorf("database query error: %v", err)
@@ -83,8 +83,8 @@ func (g *GenericAdmin[T]) handleDeletePost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Delete the object using its ID
-	err = qs.Delete(objectID)
+	// Delete the object and whatever its on_delete rules reach
+	_, _, err = qs.Filter(queryset.Q{"id": objectID}).Delete()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Delete failed: %v", err), http.StatusInternalServerError)
 		return
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -91,20 +92,20 @@ func BlacklistToken(database *db.DB, refreshToken string) error {
 		return err
 	}
 
-	// Find in outstanding
-	qs := queryset.NewQuerySet[*OutstandingToken](database)
-	token, err := qs.Filter(queryset.Q{"jti": claims.JTI}).Get()
-	if err != nil {
-		return errors.New("token not found in outstanding list")
-	}
-
-	blacklist := &BlacklistedToken{
-		TokenID: token.ID,
-		Token:   refreshToken,
-	}
-
-	blQs := queryset.NewQuerySet[*BlacklistedToken](database)
-	return blQs.Create(blacklist)
+	// Lookup and blacklist insert happen together or not at all
+	return database.Atomic(context.Background(), func(tx *db.DB) error {
+		// Find in outstanding
+		qs := queryset.NewQuerySet[*OutstandingToken](tx)
+		token, err := qs.Filter(queryset.Q{"jti": claims.JTI}).Get()
+		if err != nil {
+			return errors.New("token not found in outstanding list")
+		}
+
+		// Blacklisting an already blacklisted token is a no-op
+		blQs := queryset.NewQuerySet[*BlacklistedToken](tx)
+		_, _, err = blQs.GetOrCreate(queryset.Q{"token_id": token.ID}, map[string]interface{}{"token": refreshToken})
+		return err
+	})
 }
 
 func createToken(userID uint64, jti, tokenType string, duration time.Duration) (string, error) {
@@ -50,7 +50,7 @@ func AuthenticationMiddleware(database *db.DB, userModel interface{}) func(http.
 			}
 
 			// Query user
-			sql := fmt.Sprintf("SELECT * FROM %s WHERE id = $1 LIMIT 1", tableName)
+			sql := fmt.Sprintf("SELECT * FROM %s WHERE id = %s LIMIT 1", tableName, database.Ops().Placeholder(1))
 			rows, err := database.Query(sql, claims.UserID)
 			if err != nil {
 				next.ServeHTTP(w, r)
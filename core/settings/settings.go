@@ -43,6 +43,11 @@ type DatabaseConfig struct {
 	Port     int               `json:"port" yaml:"port" env:"DB_PORT"`
 	Pool     PoolConfig        `json:"pool" yaml:"pool"`
 	Options  map[string]string `json:"options" yaml:"options"`
+
+	// StatementTimeout bounds queries whose context has no deadline (0 = none)
+	StatementTimeout time.Duration `json:"statement_timeout" yaml:"statement_timeout"`
+	// SlowQueryThreshold logs statements taking at least this long (0 = off)
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold" yaml:"slow_query_threshold"`
 }
 
 // PoolConfig for connection pooling
@@ -237,9 +242,13 @@ func processTags(obj interface{}) error {
 			for _, key := range field.MapKeys() {
 				val := field.MapIndex(key)
 				if val.Kind() == reflect.Struct {
-					// Maps are tricky to mutate in-place via reflect without pointers
-					// For our DatabaeConfig, it's often small so we'll handle common cases
-					// If it's a map[string]Struct, we can't easily recurse and mutate unless it's map[string]*Struct
+					// Map values aren't addressable, so process a copy and store it back
+					cp := reflect.New(val.Type())
+					cp.Elem().Set(val)
+					if err := processTags(cp.Interface()); err != nil {
+						return fmt.Errorf("%s[%v]: %w", fieldType.Name, key.Interface(), err)
+					}
+					field.SetMapIndex(key, cp.Elem())
 				}
 			}
 		}
@@ -157,3 +157,36 @@ func TestPoolConfigDuration(t *testing.T) {
 		t.Errorf("Expected default MaxLifetime 5m, got %v", pc.MaxLifetime)
 	}
 }
+
+func TestDatabaseDefaults(t *testing.T) {
+	yamlContent := `
+secret_key: "yaml-secret-key-long-enough-to-pass-validation-32"
+databases:
+  default:
+    engine: postgres
+    name: app
+    statement_timeout: 5s
+    pool:
+      max_open: 50
+`
+	tmpfile, _ := os.CreateTemp("", "settings*.yaml")
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Write([]byte(yamlContent))
+	tmpfile.Close()
+
+	s, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load YAML: %v", err)
+	}
+
+	cfg := s.Databases["default"]
+	if cfg.Host != "localhost" {
+		t.Errorf("Expected default host localhost, got %q", cfg.Host)
+	}
+	if cfg.Pool.MaxOpen != 50 || cfg.Pool.MaxIdle != 5 || cfg.Pool.MaxLifetime != 5*time.Minute {
+		t.Errorf("Expected pool defaults to fill unset values, got %+v", cfg.Pool)
+	}
+	if cfg.StatementTimeout != 5*time.Second {
+		t.Errorf("Expected statement timeout 5s, got %v", cfg.StatementTimeout)
+	}
+}
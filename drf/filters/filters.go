@@ -2,6 +2,8 @@ package filters
 
 import (
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/anuragcarret/djang-drf-go/orm/queryset"
@@ -36,24 +38,35 @@ func (f *DjangoFilterBackend) FilterQueryset(qs interface{}, params url.Values)
 		}
 
 		// Check for lookup expressions
-		lookups := []string{"gt", "gte", "lt", "lte", "contains", "icontains", "in", "iexact"}
+		lookups := []string{"gt", "gte", "lt", "lte", "ne", "contains", "icontains", "startswith", "istartswith",
+			"endswith", "iendswith", "in", "range", "isnull", "iexact", "date", "year", "month", "day"}
 		for _, lookup := range lookups {
 			key := field + "__" + lookup
 			if val := params.Get(key); val != "" {
-				// Handle 'in' lookup specially (comma-separated values)
-				if lookup == "in" {
+				switch lookup {
+				case "in":
+					// Comma-separated values
 					filters[key] = strings.Split(val, ",")
-				} else {
+				case "range":
+					// "low,high"
+					if bounds := strings.Split(val, ","); len(bounds) == 2 {
+						filters[key] = bounds
+					}
+				case "isnull":
+					if isNull, err := strconv.ParseBool(val); err == nil {
+						filters[key] = isNull
+					}
+				default:
 					filters[key] = val
 				}
 			}
 		}
 	}
 
-	// Apply filters to queryset
-	// This would call qs.Filter(filters) if we have the right type
-	// For now, return as-is (implementation depends on QuerySet interface)
-	return qs
+	if len(filters) == 0 {
+		return qs
+	}
+	return applyFilter(qs, filters)
 }
 
 // SearchFilter implements text search across multiple fields
@@ -81,17 +94,31 @@ func (f *SearchFilter) FilterQueryset(qs interface{}, params url.Values) interfa
 		return qs
 	}
 
-	// Build OR filters for all search fields
-	// In a real implementation, this would use QuerySet's Q objects for OR queries
-	// For now, we'll build filters for each field with icontains
+	if len(f.SearchFields) == 0 {
+		return qs
+	}
 
-	filters := make(queryset.Q)
-	for _, field := range f.SearchFields {
-		key := field + "__icontains"
-		filters[key] = searchTerm
+	// Every whitespace-separated term must match at least one search field
+	var terms []queryset.Node
+	for _, term := range strings.Fields(searchTerm) {
+		var fields []queryset.Node
+		for _, field := range f.SearchFields {
+			fields = append(fields, queryset.Q{field + "__icontains": term})
+		}
+		terms = append(terms, queryset.Or(fields...))
 	}
 
-	return qs
+	return applyFilter(qs, queryset.And(terms...))
+}
+
+// applyFilter calls Filter on a generic *queryset.QuerySet[T], returning qs
+// unchanged if it has no Filter method
+func applyFilter(qs interface{}, node queryset.Node) interface{} {
+	method := reflect.ValueOf(qs).MethodByName("Filter")
+	if !method.IsValid() {
+		return qs
+	}
+	return method.Call([]reflect.Value{reflect.ValueOf(node)})[0].Interface()
 }
 
 // OrderingFilter implements result ordering
@@ -148,9 +175,17 @@ func (f *OrderingFilter) FilterQueryset(qs interface{}, params url.Values) inter
 		return qs
 	}
 
-	// Apply ordering to queryset
-	// This would call qs.OrderBy(validOrdering...) if we have the right type
-	return qs
+	return applyOrdering(qs, validOrdering)
+}
+
+// applyOrdering calls OrderBy on a generic *queryset.QuerySet[T], returning
+// qs unchanged if it has no OrderBy method
+func applyOrdering(qs interface{}, ordering []string) interface{} {
+	method := reflect.ValueOf(qs).MethodByName("OrderBy")
+	if !method.IsValid() {
+		return qs
+	}
+	return method.CallSlice([]reflect.Value{reflect.ValueOf(ordering)})[0].Interface()
 }
 
 // CombineFilters applies multiple filter backends in sequence
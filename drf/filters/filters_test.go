@@ -2,79 +2,197 @@ package filters
 
 import (
 	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/orm/queryset"
 )
 
+type article struct {
+	ID          uint64    `drf:"id;primary_key"`
+	Title       string    `drf:"title"`
+	Body        string    `drf:"body"`
+	Views       int64     `drf:"views"`
+	AuthorID    uint64    `drf:"author_id;foreign_key=authors.id"`
+	PublishedAt time.Time `drf:"published_at;null"`
+}
+
+func (a *article) TableName() string { return "articles" }
+
 // TestDjangoFilterBackend tests field-based filtering
 func TestDjangoFilterBackend(t *testing.T) {
+	backend := NewDjangoFilterBackend([]string{"title", "views", "published_at", "author__name"})
+	base := queryset.NewQuerySet[*article](nil)
+
+	filter := func(params map[string]string) (string, []interface{}) {
+		qs := backend.FilterQueryset(base, createQueryParams(params)).(*queryset.QuerySet[*article])
+		sql, args, err := qs.SQL()
+		if err != nil {
+			t.Fatalf("SQL failed: %v", err)
+		}
+		return sql, args
+	}
+
 	t.Run("Filters by exact match", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, args := filter(map[string]string{"title": "Go"})
+		if !strings.HasSuffix(sql, "WHERE articles.title = $1") || args[0] != "Go" {
+			t.Errorf("unexpected SQL %q with args %v", sql, args)
+		}
 	})
 
 	t.Run("Filters with lookup expressions (gt, lt, gte, lte)", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, _ := filter(map[string]string{"views__gte": "10", "views__lt": "20"})
+		if !strings.HasSuffix(sql, "WHERE articles.views >= $1 AND articles.views < $2") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 
 	t.Run("Filters with contains/icontains", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, args := filter(map[string]string{"title__icontains": "go"})
+		if !strings.HasSuffix(sql, "WHERE articles.title ILIKE $1 ESCAPE '\\'") || args[0] != "%go%" {
+			t.Errorf("unexpected SQL %q with args %v", sql, args)
+		}
 	})
 
 	t.Run("Filters with in lookup", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, args := filter(map[string]string{"views__in": "1,2,3"})
+		if !strings.HasSuffix(sql, "WHERE articles.views IN ($1, $2, $3)") || len(args) != 3 {
+			t.Errorf("unexpected SQL %q with args %v", sql, args)
+		}
 	})
 
 	t.Run("Combines multiple filters with AND", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, _ := filter(map[string]string{"title": "Go", "author__name__icontains": "ann"})
+		expected := "FROM articles INNER JOIN authors ON articles.author_id = authors.id WHERE authors.name ILIKE $1 ESCAPE '\\' AND articles.title = $2"
+		if !strings.HasSuffix(sql, expected) {
+			t.Errorf("expected SQL ending in %q, got %q", expected, sql)
+		}
+	})
+
+	t.Run("Ignores fields that are not allowed", func(t *testing.T) {
+		if qs := backend.FilterQueryset(base, createQueryParams(map[string]string{"body": "x"})); qs != base {
+			t.Error("expected the queryset to be returned unchanged")
+		}
+	})
+
+	t.Run("Filters with startswith, range and isnull", func(t *testing.T) {
+		sql, args := filter(map[string]string{"title__startswith": "Go", "views__range": "10,20", "published_at__isnull": "false"})
+		expected := "WHERE articles.published_at IS NOT NULL AND articles.title LIKE $1 ESCAPE '\\' AND articles.views BETWEEN $2 AND $3"
+		if !strings.HasSuffix(sql, expected) || args[0] != "Go%" {
+			t.Errorf("expected SQL ending in %q, got %q with args %v", expected, sql, args)
+		}
 	})
 
 	t.Run("Handles date filters", func(t *testing.T) {
-		t.Skip("DjangoFilterBackend not yet implemented")
+		sql, _ := filter(map[string]string{"published_at__year": "2024"})
+		if !strings.HasSuffix(sql, "WHERE EXTRACT(YEAR FROM articles.published_at) = $1") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 }
 
 // TestSearchFilter tests text search across multiple fields
 func TestSearchFilter(t *testing.T) {
+	search := NewSearchFilter([]string{"title", "body"})
+	base := queryset.NewQuerySet[*article](nil)
+
 	t.Run("Searches across configured fields", func(t *testing.T) {
-		t.Skip("SearchFilter not yet implemented")
+		qs := search.FilterQueryset(base, createQueryParams(map[string]string{"search": "go"})).(*queryset.QuerySet[*article])
+		sql, args, _ := qs.SQL()
+		if !strings.Contains(sql, "WHERE (articles.title ILIKE $1 ESCAPE '\\' OR articles.body ILIKE $2 ESCAPE '\\')") {
+			t.Errorf("expected OR across search fields, got %q", sql)
+		}
+		if len(args) != 2 || args[0] != "%go%" {
+			t.Errorf("unexpected args %v", args)
+		}
 	})
 
 	t.Run("Performs case-insensitive search", func(t *testing.T) {
-		t.Skip("SearchFilter not yet implemented")
+		qs := search.FilterQueryset(base, createQueryParams(map[string]string{"search": "Go orm"})).(*queryset.QuerySet[*article])
+		sql, _, _ := qs.SQL()
+		expected := "WHERE ((articles.title ILIKE $1 ESCAPE '\\' OR articles.body ILIKE $2 ESCAPE '\\') AND (articles.title ILIKE $3 ESCAPE '\\' OR articles.body ILIKE $4 ESCAPE '\\'))"
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected every term to match case-insensitively, got %q", sql)
+		}
 	})
 
 	t.Run("Searches related fields (field__subfield)", func(t *testing.T) {
-		t.Skip("SearchFilter not yet implemented")
+		related := NewSearchFilter([]string{"title", "author__name"})
+		qs := related.FilterQueryset(base, createQueryParams(map[string]string{"search": "ann"})).(*queryset.QuerySet[*article])
+		sql, _, _ := qs.SQL()
+		expected := "FROM articles INNER JOIN authors ON articles.author_id = authors.id WHERE (articles.title ILIKE $1 ESCAPE '\\' OR authors.name ILIKE $2 ESCAPE '\\')"
+		if !strings.HasSuffix(sql, expected) {
+			t.Errorf("expected SQL ending in %q, got %q", expected, sql)
+		}
 	})
 
 	t.Run("Sanitizes search input", func(t *testing.T) {
-		t.Skip("SearchFilter not yet implemented")
+		qs := search.FilterQueryset(base, createQueryParams(map[string]string{"search": "'; DROP TABLE articles; --"})).(*queryset.QuerySet[*article])
+		sql, _, _ := qs.SQL()
+		if strings.Contains(sql, "DROP") {
+			t.Errorf("search term must be passed as an argument, got %q", sql)
+		}
 	})
 
 	t.Run("Returns empty when search term empty", func(t *testing.T) {
-		t.Skip("SearchFilter not yet implemented")
+		if qs := search.FilterQueryset(base, createQueryParams(map[string]string{"search": "   "})); qs != base {
+			t.Error("expected the queryset to be returned unchanged")
+		}
 	})
 }
 
 // TestOrderingFilter tests result ordering
 func TestOrderingFilter(t *testing.T) {
+	ordering := NewOrderingFilter([]string{"title", "views", "author__name"})
+	base := queryset.NewQuerySet[*article](nil)
+
+	order := func(f *OrderingFilter, param string) string {
+		params := url.Values{}
+		if param != "" {
+			params.Set("ordering", param)
+		}
+		sql, _, _ := f.FilterQueryset(base, params).(*queryset.QuerySet[*article]).SQL()
+		return sql
+	}
+
 	t.Run("Orders by single field", func(t *testing.T) {
-		t.Skip("OrderingFilter not yet implemented")
+		if sql := order(ordering, "title"); !strings.HasSuffix(sql, "ORDER BY articles.title") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 
 	t.Run("Orders by multiple fields", func(t *testing.T) {
-		t.Skip("OrderingFilter not yet implemented")
+		if sql := order(ordering, "views,title"); !strings.HasSuffix(sql, "ORDER BY articles.views, articles.title") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 
 	t.Run("Supports descending with - prefix", func(t *testing.T) {
-		t.Skip("OrderingFilter not yet implemented")
+		if sql := order(ordering, "-views"); !strings.HasSuffix(sql, "ORDER BY articles.views DESC") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
+	})
+
+	t.Run("Orders across relations", func(t *testing.T) {
+		expected := "LEFT JOIN authors ON articles.author_id = authors.id ORDER BY authors.name"
+		if sql := order(ordering, "author__name"); !strings.HasSuffix(sql, expected) {
+			t.Errorf("expected SQL ending in %q, got %q", expected, sql)
+		}
 	})
 
 	t.Run("Uses default ordering when not specified", func(t *testing.T) {
-		t.Skip("OrderingFilter not yet implemented")
+		withDefault := NewOrderingFilter([]string{"title"})
+		withDefault.DefaultOrdering = []string{"-title"}
+		if sql := order(withDefault, ""); !strings.HasSuffix(sql, "ORDER BY articles.title DESC") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 
 	t.Run("Validates ordering fields against whitelist", func(t *testing.T) {
-		t.Skip("OrderingFilter not yet implemented")
+		if sql := order(ordering, "body,-views"); !strings.HasSuffix(sql, "ORDER BY articles.views DESC") {
+			t.Errorf("unexpected SQL %q", sql)
+		}
 	})
 }
 
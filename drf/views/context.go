@@ -1,6 +1,7 @@
 package views
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -16,6 +17,15 @@ type Context struct {
 	User           interface{}
 }
 
+// Context returns the request context so ORM queries are cancelled when the
+// client disconnects. Falls back to context.Background without a request.
+func (c *Context) Context() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
 // ParseRequest parses the request body based on Content-Type
 func (c *Context) ParseRequest() error {
 	ct := c.Request.Header.Get("Content-Type")
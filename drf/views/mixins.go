@@ -1,6 +1,7 @@
 package views
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
@@ -17,7 +18,7 @@ type ListModelMixin[T queryset.ModelInterface] struct {
 }
 
 func (m *ListModelMixin[T]) List(c *Context) Response {
-	qs := queryset.NewQuerySet[T](m.DB)
+	qs := queryset.NewQuerySet[T](m.DB).WithContext(c.Context())
 	qs = ApplyFilters(qs, c.Query)
 
 	results, err := qs.All()
@@ -43,7 +44,7 @@ func (m *CreateModelMixin[T]) Create(c *Context) Response {
 	// Allow hooks for custom logic before creation (e.g., setting the user)
 	m.PerformCreate(c, instance)
 
-	qs := queryset.NewQuerySet[T](m.DB)
+	qs := queryset.NewQuerySet[T](m.DB).WithContext(c.Context())
 	if err := qs.Create(instance); err != nil {
 		return BadRequest(map[string]string{"error": "Failed to create: " + err.Error()})
 	}
@@ -93,7 +94,7 @@ func (m *RetrieveModelMixin[T]) Retrieve(c *Context, lookupValue string) Respons
 		lookupField = "id"
 	}
 
-	qs := queryset.NewQuerySet[T](m.DB)
+	qs := queryset.NewQuerySet[T](m.DB).WithContext(c.Context())
 
 	// Convert lookup value to uint64 for ID field
 	if lookupField == "id" {
@@ -104,18 +105,27 @@ func (m *RetrieveModelMixin[T]) Retrieve(c *Context, lookupValue string) Respons
 
 		obj, err := qs.GetByID(id)
 		if err != nil {
-			return NotFound("Object not found")
+			return lookupFailed(err)
 		}
 		return OK(obj)
 	}
 
 	// For other fields, use filter
-	results, err := qs.Filter(map[string]interface{}{lookupField: lookupValue}).All()
-	if err != nil || len(results) == 0 {
-		return NotFound("Object not found")
+	obj, err := qs.Get(queryset.Q{lookupField: lookupValue})
+	if err != nil {
+		return lookupFailed(err)
 	}
 
-	return OK(results[0])
+	return OK(obj)
+}
+
+// lookupFailed maps a failed object lookup to a response: 404 if the object
+// doesn't exist, 500 if the query failed
+func lookupFailed(err error) Response {
+	if errors.Is(err, queryset.ErrDoesNotExist) {
+		return NotFound("Object not found")
+	}
+	return InternalServerError(err.Error())
 }
 
 // UpdateModelMixin provides `Update` and `PartialUpdate` methods
@@ -137,12 +147,12 @@ func (m *UpdateModelMixin[T]) performUpdate(c *Context, id string, partial bool)
 		return BadRequest(map[string]string{"error": "Invalid ID format"})
 	}
 
-	qs := queryset.NewQuerySet[T](m.DB)
+	qs := queryset.NewQuerySet[T](m.DB).WithContext(c.Context())
 
 	// Get existing object
 	existing, err := qs.GetByID(idUint)
 	if err != nil {
-		return NotFound("Object not found")
+		return lookupFailed(err)
 	}
 
 	// Bind new data
@@ -169,16 +179,16 @@ func (m *DestroyModelMixin[T]) Destroy(c *Context, id string) Response {
 		return BadRequest(map[string]string{"error": "Invalid ID format"})
 	}
 
-	qs := queryset.NewQuerySet[T](m.DB)
+	qs := queryset.NewQuerySet[T](m.DB).WithContext(c.Context())
 
 	// Check if exists
 	_, err = qs.GetByID(idUint)
 	if err != nil {
-		return NotFound("Object not found")
+		return lookupFailed(err)
 	}
 
 	// Delete
-	if err := qs.Delete(idUint); err != nil {
+	if _, _, err := qs.Filter(queryset.Q{"id": idUint}).Delete(); err != nil {
 		return BadRequest(map[string]string{"error": "Failed to delete: " + err.Error()})
 	}
 
@@ -209,7 +219,7 @@ func (v *GenericAPIView[T]) GetObject(c *Context, lookupValue string) (T, error)
 		lookupField = "id"
 	}
 
-	qs := v.GetQueryset()
+	qs := v.GetQueryset().WithContext(c.Context())
 
 	// Convert lookup value to uint64 for ID field
 	if lookupField == "id" {
@@ -222,12 +232,7 @@ func (v *GenericAPIView[T]) GetObject(c *Context, lookupValue string) (T, error)
 	}
 
 	// For other fields, use filter
-	results, err := qs.Filter(map[string]interface{}{lookupField: lookupValue}).All()
-	if err != nil || len(results) == 0 {
-		return zero, fmt.Errorf("object not found")
-	}
-
-	return results[0], nil
+	return qs.Get(queryset.Q{lookupField: lookupValue})
 }
 
 func (v *GenericAPIView[T]) FilterQueryset(qs *queryset.QuerySet[T], params url.Values) *queryset.QuerySet[T] {
@@ -32,7 +32,7 @@ func (v *ModelViewSet[T]) GetDepth() int {
 }
 
 func (v *ModelViewSet[T]) List(c *Context) Response {
-	qs := queryset.NewQuerySet[T](v.DB)
+	qs := queryset.NewQuerySet[T](v.DB).WithContext(c.Context())
 	qs = ApplyFilters(qs, c.Query)
 
 	// Basic total count for pagination
@@ -72,7 +72,7 @@ func (v *ModelViewSet[T]) Create(c *Context) Response {
 }
 
 func (v *ModelViewSet[T]) PerformCreate(c *Context, obj T) error {
-	qs := queryset.NewQuerySet[T](v.DB)
+	qs := queryset.NewQuerySet[T](v.DB).WithContext(c.Context())
 	return qs.Create(obj)
 }
 
@@ -8,3 +8,5 @@ require (
 )
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/mattn/go-sqlite3 v1.14.33
@@ -0,0 +1,304 @@
+// Package connections manages named database connections built from
+// settings.Databases, similar to django.db.connections.
+package connections
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anuragcarret/djang-drf-go/core/settings"
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// DefaultAlias is the alias used when none is given
+const DefaultAlias = "default"
+
+// Common errors
+var (
+	ErrConnectionNotFound = errors.New("database alias not configured")
+	ErrHandlerClosed      = errors.New("connection handler is closed")
+)
+
+// Handler lazily opens and caches one *db.DB per configured alias.
+type Handler struct {
+	configs map[string]settings.DatabaseConfig
+	conns   map[string]*db.DB
+	routers []Router
+	closed  bool
+	mu      sync.Mutex
+}
+
+// NewHandler creates a handler for the given database configurations.
+// Connections are opened on first use.
+func NewHandler(configs map[string]settings.DatabaseConfig) *Handler {
+	h := &Handler{conns: make(map[string]*db.DB)}
+	h.setConfigs(configs)
+	return h
+}
+
+func (h *Handler) setConfigs(configs map[string]settings.DatabaseConfig) {
+	h.configs = make(map[string]settings.DatabaseConfig, len(configs))
+	for alias, cfg := range configs {
+		h.configs[alias] = cfg
+	}
+}
+
+// Configure replaces the database configurations, closing any connections
+// opened under the previous configuration.
+func (h *Handler) Configure(configs map[string]settings.DatabaseConfig) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.closeLocked()
+	h.setConfigs(configs)
+	h.closed = false
+	return err
+}
+
+// Get returns the connection for alias, opening it on first use.
+// An empty alias means DefaultAlias.
+func (h *Handler) Get(alias string) (*db.DB, error) {
+	if alias == "" {
+		alias = DefaultAlias
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHandlerClosed
+	}
+	if conn, ok := h.conns[alias]; ok {
+		return conn, nil
+	}
+
+	cfg, ok := h.configs[alias]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrConnectionNotFound, alias)
+	}
+
+	conn, err := Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("database %q: %w", alias, err)
+	}
+	h.conns[alias] = conn
+	return conn, nil
+}
+
+// Dialect returns the SQL dialect of alias without opening a connection:
+// that of the open connection, or else of the configured engine. An empty
+// alias means DefaultAlias.
+func (h *Handler) Dialect(alias string) (db.Dialect, error) {
+	if alias == "" {
+		alias = DefaultAlias
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if conn, ok := h.conns[alias]; ok && !h.closed {
+		return conn.Ops(), nil
+	}
+	cfg, ok := h.configs[alias]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrConnectionNotFound, alias)
+	}
+	d, err := db.GetDialect(cfg.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("database %q: %w", alias, err)
+	}
+	return d, nil
+}
+
+// MustGet is like Get but panics on error
+func (h *Handler) MustGet(alias string) *db.DB {
+	conn, err := h.Get(alias)
+	if err != nil {
+		panic(err)
+	}
+	return conn
+}
+
+// Register installs an already opened connection under alias, taking
+// precedence over any configuration for it. The handler takes ownership
+// and closes it in Close.
+func (h *Handler) Register(alias string, conn *db.DB) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[alias] = conn
+	h.closed = false
+}
+
+// Aliases returns all configured and registered aliases, sorted
+func (h *Handler) Aliases() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var aliases []string
+	for alias := range h.configs {
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+	for alias := range h.conns {
+		if !seen[alias] {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// Close closes every open connection. Further calls to Get fail with
+// ErrHandlerClosed until Configure or Register is called again.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	return h.closeLocked()
+}
+
+func (h *Handler) closeLocked() error {
+	var errs []error
+	for alias, conn := range h.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("database %q: %w", alias, err))
+		}
+		delete(h.conns, alias)
+	}
+	return errors.Join(errs...)
+}
+
+// Open connects to the database described by cfg and applies its pool
+// limits, statement timeout and slow-query logging.
+func Open(cfg settings.DatabaseConfig) (*db.DB, error) {
+	dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.NewDB(cfg.Engine, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetPool(cfg.Pool.MaxOpen, cfg.Pool.MaxIdle, cfg.Pool.MaxLifetime)
+	conn.SetStatementTimeout(cfg.StatementTimeout)
+	if cfg.SlowQueryThreshold > 0 {
+		conn.AddQueryHook(&db.SlowQueryLogger{Threshold: cfg.SlowQueryThreshold})
+	}
+	return conn, nil
+}
+
+// DSN builds a driver connection string from cfg.
+// Options are passed through as driver parameters (e.g. sslmode for
+// Postgres, _foreign_keys for SQLite).
+func DSN(cfg settings.DatabaseConfig) (string, error) {
+	dialect, err := db.GetDialect(cfg.Engine)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect.Name() {
+	case "postgres":
+		return postgresDSN(cfg), nil
+	case "sqlite3":
+		return sqliteDSN(cfg)
+	}
+	return "", fmt.Errorf("no DSN builder for engine %q", cfg.Engine)
+}
+
+// postgresDSN builds a lib/pq key=value connection string
+func postgresDSN(cfg settings.DatabaseConfig) string {
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+"="+quoteDSNValue(value))
+		}
+	}
+
+	add("host", cfg.Host)
+	if cfg.Port > 0 {
+		add("port", fmt.Sprint(cfg.Port))
+	}
+	add("user", cfg.User)
+	add("password", cfg.Password)
+	add("dbname", cfg.Name)
+	for _, key := range sortedKeys(cfg.Options) {
+		add(key, cfg.Options[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes values containing spaces, quotes or backslashes
+func quoteDSNValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// sqliteDSN uses Name as the database file, with Options as query parameters
+func sqliteDSN(cfg settings.DatabaseConfig) (string, error) {
+	if cfg.Name == "" {
+		return "", errors.New("sqlite3 requires a database name (file path or :memory:)")
+	}
+	if len(cfg.Options) == 0 {
+		return cfg.Name, nil
+	}
+
+	params := url.Values{}
+	for key, value := range cfg.Options {
+		params.Set(key, value)
+	}
+	sep := "?"
+	if strings.Contains(cfg.Name, "?") {
+		sep = "&"
+	}
+	return cfg.Name + sep + params.Encode(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Connections is the global handler used by the package-level functions
+var Connections = NewHandler(nil)
+
+// Configure replaces the global database configurations.
+// Typically called once at startup with settings.Get().Databases.
+func Configure(configs map[string]settings.DatabaseConfig) error {
+	return Connections.Configure(configs)
+}
+
+// Get returns the global connection for alias
+func Get(alias string) (*db.DB, error) {
+	return Connections.Get(alias)
+}
+
+// Dialect returns the SQL dialect of the global connection for alias,
+// without opening it
+func Dialect(alias string) (db.Dialect, error) {
+	return Connections.Dialect(alias)
+}
+
+// MustGet returns the global connection for alias, panicking on error
+func MustGet(alias string) *db.DB {
+	return Connections.MustGet(alias)
+}
+
+// Close closes all global connections; call it on shutdown
+func Close() error {
+	return Connections.Close()
+}
@@ -0,0 +1,106 @@
+package connections
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/core/settings"
+)
+
+func TestDSN(t *testing.T) {
+	dsn, err := DSN(settings.DatabaseConfig{
+		Engine:   "postgres",
+		Name:     "app",
+		User:     "admin",
+		Password: "it's secret",
+		Host:     "db.internal",
+		Port:     5432,
+		Options:  map[string]string{"sslmode": "disable", "application_name": "api"},
+	})
+	if err != nil {
+		t.Fatalf("DSN failed: %v", err)
+	}
+	expected := `host=db.internal port=5432 user=admin password='it\'s secret' dbname=app application_name=api sslmode=disable`
+	if dsn != expected {
+		t.Errorf("expected %q, got %q", expected, dsn)
+	}
+
+	dsn, err = DSN(settings.DatabaseConfig{
+		Engine:  "sqlite3",
+		Name:    "app.db",
+		Options: map[string]string{"_foreign_keys": "on"},
+	})
+	if err != nil {
+		t.Fatalf("DSN failed: %v", err)
+	}
+	if dsn != "app.db?_foreign_keys=on" {
+		t.Errorf("unexpected sqlite DSN %q", dsn)
+	}
+
+	if _, err := DSN(settings.DatabaseConfig{Engine: "sqlite3"}); err == nil {
+		t.Error("expected error for sqlite without a name")
+	}
+	if _, err := DSN(settings.DatabaseConfig{Engine: "oracle"}); err == nil {
+		t.Error("expected error for unknown engine")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(map[string]settings.DatabaseConfig{
+		"default": {
+			Engine:           "sqlite3",
+			Name:             filepath.Join(t.TempDir(), "default.db"),
+			Pool:             settings.PoolConfig{MaxOpen: 3, MaxIdle: 2, MaxLifetime: time.Minute},
+			StatementTimeout: 2 * time.Second,
+		},
+		"memory": {Engine: "sqlite", Name: ":memory:", Pool: settings.PoolConfig{MaxOpen: 10}},
+	})
+
+	if d, err := h.Dialect("memory"); err != nil || d.Name() != "sqlite3" {
+		t.Errorf("expected the sqlite3 dialect, got %v, %v", d, err)
+	}
+	if _, open := h.conns["memory"]; open {
+		t.Error("expected Dialect not to open a connection")
+	}
+	if _, err := h.Dialect("replica"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("expected ErrConnectionNotFound, got %v", err)
+	}
+
+	conn, err := h.Get("")
+	if err != nil {
+		t.Fatalf("Get default failed: %v", err)
+	}
+	if again := h.MustGet("default"); again != conn {
+		t.Error("expected the cached connection to be reused")
+	}
+	if conn.Stats().MaxOpenConnections != 3 {
+		t.Errorf("expected MaxOpen 3, got %d", conn.Stats().MaxOpenConnections)
+	}
+	if conn.StatementTimeout() != 2*time.Second {
+		t.Errorf("expected statement timeout to be applied, got %v", conn.StatementTimeout())
+	}
+
+	mem := h.MustGet("memory")
+	if mem.Stats().MaxOpenConnections != 1 {
+		t.Errorf("in-memory sqlite must stay on one connection, got %d", mem.Stats().MaxOpenConnections)
+	}
+
+	if _, err := h.Get("replica"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("expected ErrConnectionNotFound, got %v", err)
+	}
+	if aliases := h.Aliases(); len(aliases) != 2 || aliases[0] != "default" || aliases[1] != "memory" {
+		t.Errorf("unexpected aliases %v", aliases)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := h.Get("default"); !errors.Is(err, ErrHandlerClosed) {
+		t.Errorf("expected ErrHandlerClosed, got %v", err)
+	}
+	if _, err := conn.Exec("SELECT 1"); err == nil {
+		t.Error("expected connection to be closed")
+	}
+}
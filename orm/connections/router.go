@@ -0,0 +1,138 @@
+package connections
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// Router decides which database alias a model is read from, written to and
+// migrated on, mirroring Django's DATABASE_ROUTERS.
+//
+// Returning "" from DBForRead or DBForWrite, or ok=false from AllowMigrate,
+// means the router has no opinion and the next router is consulted.
+type Router interface {
+	DBForRead(model interface{}) string
+	DBForWrite(model interface{}) string
+	AllowMigrate(alias, table string) (allow bool, ok bool)
+}
+
+// SetRouters installs the routers consulted, in order, by DBForRead,
+// DBForWrite and AllowMigrate
+func (h *Handler) SetRouters(routers ...Router) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routers = append([]Router(nil), routers...)
+}
+
+func (h *Handler) getRouters() []Router {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.routers
+}
+
+// DBForRead returns the alias to read model from. Reads on a context that
+// is pinned to the primary (see WithPinning) are routed like writes.
+func (h *Handler) DBForRead(ctx context.Context, model interface{}) string {
+	if IsPinned(ctx) {
+		return h.route(func(r Router) string { return r.DBForWrite(model) })
+	}
+	return h.route(func(r Router) string { return r.DBForRead(model) })
+}
+
+// DBForWrite returns the alias to write model to, and pins ctx to the
+// primary if it carries pinning state
+func (h *Handler) DBForWrite(ctx context.Context, model interface{}) string {
+	if p := pinFrom(ctx); p != nil {
+		p.Store(true)
+	}
+	return h.route(func(r Router) string { return r.DBForWrite(model) })
+}
+
+func (h *Handler) route(pick func(Router) string) string {
+	for _, r := range h.getRouters() {
+		if alias := pick(r); alias != "" {
+			return alias
+		}
+	}
+	return DefaultAlias
+}
+
+// AllowMigrate reports whether migrations touching table may run on alias.
+// Without an opinion from any router, migrations are allowed.
+func (h *Handler) AllowMigrate(alias, table string) bool {
+	for _, r := range h.getRouters() {
+		if allow, ok := r.AllowMigrate(alias, table); ok {
+			return allow
+		}
+	}
+	return true
+}
+
+// ReplicaRouter sends writes to Primary and spreads reads randomly across
+// Replicas. Migrations are only allowed on Primary.
+type ReplicaRouter struct {
+	Primary  string
+	Replicas []string
+}
+
+func (r *ReplicaRouter) DBForRead(model interface{}) string {
+	if len(r.Replicas) == 0 {
+		return r.Primary
+	}
+	return r.Replicas[rand.Intn(len(r.Replicas))]
+}
+
+func (r *ReplicaRouter) DBForWrite(model interface{}) string {
+	return r.Primary
+}
+
+func (r *ReplicaRouter) AllowMigrate(alias, table string) (bool, bool) {
+	return alias == r.Primary, true
+}
+
+type pinKey struct{}
+
+// WithPinning returns a context that starts routing reads to the primary as
+// soon as a write is routed through it, so a request reads its own writes
+// despite replica lag.
+func WithPinning(ctx context.Context) context.Context {
+	if pinFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, pinKey{}, new(atomic.Bool))
+}
+
+// PinToPrimary returns a context whose reads are always routed to the primary
+func PinToPrimary(ctx context.Context) context.Context {
+	p := new(atomic.Bool)
+	p.Store(true)
+	return context.WithValue(ctx, pinKey{}, p)
+}
+
+// IsPinned reports whether reads on ctx are routed to the primary
+func IsPinned(ctx context.Context) bool {
+	p := pinFrom(ctx)
+	return p != nil && p.Load()
+}
+
+func pinFrom(ctx context.Context) *atomic.Bool {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(pinKey{}).(*atomic.Bool)
+	return p
+}
+
+// PinningMiddleware enables WithPinning for every request
+func PinningMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithPinning(r.Context())))
+	})
+}
+
+// SetRouters installs routers on the global handler
+func SetRouters(routers ...Router) {
+	Connections.SetRouters(routers...)
+}
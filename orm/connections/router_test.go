@@ -0,0 +1,84 @@
+package connections
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type analyticsEvent struct{}
+
+// analyticsRouter keeps analyticsEvent on its own database
+type analyticsRouter struct{}
+
+func (analyticsRouter) DBForRead(model interface{}) string {
+	if _, ok := model.(*analyticsEvent); ok {
+		return "analytics"
+	}
+	return ""
+}
+
+func (r analyticsRouter) DBForWrite(model interface{}) string { return r.DBForRead(model) }
+
+func (analyticsRouter) AllowMigrate(alias, table string) (bool, bool) {
+	if table == "analytics_events" {
+		return alias == "analytics", true
+	}
+	return false, false
+}
+
+func TestRouting(t *testing.T) {
+	h := NewHandler(nil)
+	h.SetRouters(analyticsRouter{}, &ReplicaRouter{Primary: "default", Replicas: []string{"replica"}})
+	ctx := context.Background()
+
+	if alias := h.DBForRead(ctx, &analyticsEvent{}); alias != "analytics" {
+		t.Errorf("expected first router to win, got %q", alias)
+	}
+	if alias := h.DBForRead(ctx, nil); alias != "replica" {
+		t.Errorf("expected reads on replica, got %q", alias)
+	}
+	if alias := h.DBForWrite(ctx, nil); alias != "default" {
+		t.Errorf("expected writes on primary, got %q", alias)
+	}
+
+	if !h.AllowMigrate("analytics", "analytics_events") || h.AllowMigrate("default", "analytics_events") {
+		t.Error("analytics_events must only migrate on analytics")
+	}
+	if !h.AllowMigrate("default", "users") || h.AllowMigrate("replica", "users") {
+		t.Error("other tables must only migrate on the primary")
+	}
+
+	if alias := NewHandler(nil).DBForRead(ctx, nil); alias != DefaultAlias {
+		t.Errorf("expected %q without routers, got %q", DefaultAlias, alias)
+	}
+}
+
+func TestPinning(t *testing.T) {
+	h := NewHandler(nil)
+	h.SetRouters(&ReplicaRouter{Primary: "default", Replicas: []string{"replica"}})
+
+	ctx := WithPinning(context.Background())
+	if alias := h.DBForRead(ctx, nil); alias != "replica" {
+		t.Errorf("expected replica before any write, got %q", alias)
+	}
+	h.DBForWrite(ctx, nil)
+	if alias := h.DBForRead(ctx, nil); alias != "default" {
+		t.Errorf("expected reads pinned to primary after a write, got %q", alias)
+	}
+
+	if alias := h.DBForRead(PinToPrimary(context.Background()), nil); alias != "default" {
+		t.Errorf("expected PinToPrimary to route reads to primary, got %q", alias)
+	}
+
+	var pinned bool
+	handler := PinningMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.DBForWrite(r.Context(), nil)
+		pinned = IsPinned(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	if !pinned {
+		t.Error("expected middleware to enable pinning for the request")
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrCopyUnsupported is returned by CopyFrom on databases whose dialect has
+// no COPY protocol
+var ErrCopyUnsupported = errors.New("COPY is only supported on PostgreSQL")
+
+// CopyFrom loads rows into table with PostgreSQL's COPY protocol, which is
+// much faster than INSERT for large loads. It runs in a transaction (a
+// savepoint if one is already open) and returns the number of rows copied.
+// Hooks see a single "COPY" statement.
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if db.ops == nil || !db.ops.SupportsCopy() {
+		return 0, ErrCopyUnsupported
+	}
+
+	query := pq.CopyIn(table, columns...)
+	var copied int64
+	err := db.Atomic(ctx, func(tx *DB) error {
+		ctx, cancel := tx.withTimeout(ctx)
+		defer cancel()
+
+		ctx, event, hooks := tx.beforeQuery(ctx, query, nil)
+		n, err := tx.copyIn(ctx, query, rows)
+		afterQuery(ctx, event, hooks, n, err)
+		copied = n
+		return err
+	})
+	return copied, err
+}
+
+func (db *DB) copyIn(ctx context.Context, query string, rows [][]interface{}) (int64, error) {
+	stmt, err := db.tx.tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, err
+		}
+	}
+	// An Exec without arguments flushes the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+	return int64(len(rows)), nil
+}
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -10,23 +12,48 @@ import (
 // DB manages the database connection and execution
 type DB struct {
 	conn    *sql.DB
+	tx      *txState // set when bound to a transaction by Atomic
 	dialect string
+	ops     Dialect
+	dsn     string
+	timeout time.Duration
+	hooks   *hookList
 }
 
-// NewDB creates a new database instance
+// executor is satisfied by both *sql.DB and *sql.Tx
+type executor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewDB creates a new database instance.
+// dialect is a registered dialect name such as "postgres" or "sqlite3".
 func NewDB(dialect, dsn string) (*DB, error) {
-	conn, err := sql.Open(dialect, dsn)
+	ops, err := GetDialect(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(ops.DriverName(), dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	if c, ok := ops.(interface{ configure(*sql.DB, string) }); ok {
+		c.configure(conn, dsn)
+	}
+
 	if err := conn.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	return &DB{
 		conn:    conn,
-		dialect: dialect,
+		dialect: ops.Name(),
+		ops:     ops,
+		dsn:     dsn,
+		hooks:   &hookList{},
 	}, nil
 }
 
@@ -35,22 +62,155 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// Rows is the result of a query. Closing it also releases the statement
+// timeout, which bounds reading the rows.
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// Close closes the rows and releases their statement timeout
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+// Row is the result of a query for a single row. Scan releases its
+// statement timeout.
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+// Scan copies the row's columns into dest and releases the statement timeout
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
+
 // Query executes a query and returns rows
-func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.conn.Query(query, args...)
+func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
 }
 
 // Exec executes a command without returning rows
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return db.conn.Exec(query, args...)
+	return db.ExecContext(context.Background(), query, args...)
 }
 
 // QueryRow executes a query that returns a single row
-func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	return db.conn.QueryRow(query, args...)
+func (db *DB) QueryRow(query string, args ...interface{}) *Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query and returns rows, honoring ctx cancellation.
+// The statement timeout also bounds reading the rows, until they are closed.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	ctx, event, hooks := db.beforeQuery(ctx, query, args)
+	rows, err := db.executor().QueryContext(ctx, query, args...)
+	afterQuery(ctx, event, hooks, -1, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// ExecContext executes a command without returning rows, honoring ctx cancellation
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	ctx, event, hooks := db.beforeQuery(ctx, query, args)
+	res, err := db.executor().ExecContext(ctx, query, args...)
+	if event != nil {
+		rowsAffected := int64(-1)
+		if err == nil {
+			rowsAffected, _ = res.RowsAffected()
+		}
+		afterQuery(ctx, event, hooks, rowsAffected, err)
+	}
+	return res, err
+}
+
+// QueryRowContext executes a query that returns a single row, honoring ctx
+// cancellation. The statement timeout also bounds scanning the row.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	ctx, cancel := db.withTimeout(ctx)
+	ctx, event, hooks := db.beforeQuery(ctx, query, args)
+	row := db.executor().QueryRowContext(ctx, query, args...)
+	if event != nil {
+		afterQuery(ctx, event, hooks, -1, row.Err())
+	}
+	return &Row{Row: row, cancel: cancel}
+}
+
+func (db *DB) executor() executor {
+	if db.tx != nil {
+		return db.tx.tx
+	}
+	return db.conn
+}
+
+// SetPool applies connection pool limits. Zero values leave the
+// database/sql defaults in place. Dialect constraints, such as a single
+// connection for in-memory SQLite, take precedence over maxOpen.
+func (db *DB) SetPool(maxOpen, maxIdle int, maxLifetime time.Duration) {
+	if maxOpen > 0 {
+		db.conn.SetMaxOpenConns(maxOpen)
+	}
+	if maxIdle > 0 {
+		db.conn.SetMaxIdleConns(maxIdle)
+	}
+	if maxLifetime > 0 {
+		db.conn.SetConnMaxLifetime(maxLifetime)
+	}
+	if c, ok := db.ops.(interface{ configure(*sql.DB, string) }); ok {
+		c.configure(db.conn, db.dsn)
+	}
+}
+
+// Stats returns connection pool statistics
+func (db *DB) Stats() sql.DBStats {
+	return db.conn.Stats()
+}
+
+// SetStatementTimeout sets the default timeout applied to statements whose
+// context has no deadline of its own. Zero disables the default.
+func (db *DB) SetStatementTimeout(d time.Duration) {
+	db.timeout = d
+}
+
+// StatementTimeout returns the default statement timeout
+func (db *DB) StatementTimeout() time.Duration {
+	return db.timeout
+}
+
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if db.timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, db.timeout)
 }
 
 // Dialect returns the database type (e.g., "postgres")
 func (db *DB) Dialect() string {
 	return db.dialect
 }
+
+// Ops returns the SQL dialect for this connection.
+// It is safe to call on a nil or zero DB, falling back to DefaultDialect.
+func (db *DB) Ops() Dialect {
+	if db == nil || db.ops == nil {
+		return DefaultDialect
+	}
+	return db.ops
+}
@@ -0,0 +1,40 @@
+// Package dbtest provides test assertions on the SQL executed by the ORM,
+// similar to Django's assertNumQueries.
+package dbtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// AssertNumQueries fails t unless fn executes exactly n statements
+func AssertNumQueries(t testing.TB, n int, fn func()) []db.QueryEvent {
+	t.Helper()
+	events := db.CaptureQueries(fn)
+	if len(events) != n {
+		t.Errorf("expected %d queries, got %d:\n%s", n, len(events), format(events))
+	}
+	return events
+}
+
+// AssertMaxQueries fails t if fn executes more than n statements, which
+// catches N+1 regressions without pinning an exact count
+func AssertMaxQueries(t testing.TB, n int, fn func()) []db.QueryEvent {
+	t.Helper()
+	events := db.CaptureQueries(fn)
+	if len(events) > n {
+		t.Errorf("expected at most %d queries, got %d:\n%s", n, len(events), format(events))
+	}
+	return events
+}
+
+func format(events []db.QueryEvent) string {
+	var b strings.Builder
+	for i, e := range events {
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, e.SQL)
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Dialect describes the SQL differences between database backends.
+// The ORM asks the dialect for placeholders, lookup operators, column types
+// and introspection instead of hardcoding a single vendor's syntax.
+type Dialect interface {
+	// Name returns the canonical dialect name (e.g., "postgres", "sqlite3")
+	Name() string
+
+	// DriverName returns the database/sql driver used to open connections
+	DriverName() string
+
+	// Placeholder returns the bind parameter for the n-th argument (1-based)
+	Placeholder(n int) string
+
+	// QuoteIdent quotes a table or column name
+	QuoteIdent(name string) string
+
+	// Operator returns the SQL template for a lookup such as "icontains".
+	// The template receives the column and the placeholder, in that order.
+	Operator(lookup string) (string, bool)
+
+	// Transform returns the SQL template for a transform such as "year",
+	// which receives the column and yields the transformed value
+	Transform(name string) (string, bool)
+
+	// Concat joins SQL expressions as text, treating NULL as empty
+	Concat(args []string) string
+
+	// DataType maps an internal field type (e.g., "AutoField", "JSONField")
+	// to a column definition. CharField and ArrayField return format strings.
+	DataType(internalType string) string
+
+	// SupportsReturning reports whether INSERT ... RETURNING is available.
+	// When false, generated IDs are read through sql.Result.LastInsertId.
+	SupportsReturning() bool
+
+	// InsertedFlag returns an expression that can be selected in the
+	// RETURNING clause of an INSERT ... ON CONFLICT DO UPDATE and is true
+	// when the row was inserted rather than updated, or "" if the backend
+	// has none
+	InsertedFlag() string
+
+	// SupportsCopy reports whether DB.CopyFrom can bulk load rows with the
+	// COPY protocol
+	SupportsCopy() bool
+
+	// SupportsCursors reports whether queries can be streamed through a
+	// server-side cursor (DECLARE ... CURSOR / FETCH) within a transaction
+	SupportsCursors() bool
+
+	// ValuesColumnAliases reports whether a VALUES list used as a table
+	// takes a column alias list, as in "(VALUES ...) AS v(id, title)".
+	// Without one, its columns are named column1, column2, ...
+	ValuesColumnAliases() bool
+
+	// CastParam renders a bind parameter typed as columnType (a column
+	// definition from DataType or ColumnType, e.g. "JSONB" or "BIGINT[]"),
+	// for places such as VALUES lists where the database can't infer it.
+	// Dialects that don't need the cast return the placeholder as is.
+	CastParam(placeholder, columnType string) string
+
+	// LimitOffset renders the LIMIT/OFFSET clause (with a leading space)
+	LimitOffset(limit, offset int) string
+
+	// ForUpdate renders the row locking clause (with a leading space) for
+	// the given table aliases (all tables if empty), or "" if the backend
+	// has no row locks
+	ForUpdate(of []string, noWait, skipLocked bool) string
+
+	// TableSchema introspects a table; returns nil if it doesn't exist
+	TableSchema(database *DB, tableName string) (*TableInfo, error)
+
+	// Tables lists all user tables in the database
+	Tables(database *DB) ([]string, error)
+}
+
+var (
+	dialects   = make(map[string]Dialect)
+	dialectsMu sync.RWMutex
+)
+
+// RegisterDialect makes a dialect available to NewDB under the given names
+func RegisterDialect(d Dialect, names ...string) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+
+	dialects[d.Name()] = d
+	for _, name := range names {
+		dialects[name] = d
+	}
+}
+
+// GetDialect returns the dialect registered under name
+func GetDialect(name string) (Dialect, error) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	d, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown database dialect %q", name)
+	}
+	return d, nil
+}
+
+// DefaultDialect is used when no connection is available (e.g., building SQL
+// on a QuerySet without a database)
+var DefaultDialect Dialect = PostgresDialect{}
+
+func init() {
+	RegisterDialect(PostgresDialect{}, "postgresql")
+	RegisterDialect(SQLiteDialect{}, "sqlite")
+}
+
+// ColumnType resolves Dialect.DataType for types that take a parameter,
+// such as CharField's max_length or ArrayField's element type
+func ColumnType(d Dialect, internalType string, arg interface{}) string {
+	t := d.DataType(internalType)
+	if strings.Contains(t, "%") {
+		return fmt.Sprintf(t, arg)
+	}
+	return t
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestGetDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"postgres", "postgres"},
+		{"postgresql", "postgres"},
+		{"sqlite3", "sqlite3"},
+		{"sqlite", "sqlite3"},
+	}
+
+	for _, tt := range tests {
+		d, err := GetDialect(tt.name)
+		if err != nil {
+			t.Fatalf("GetDialect(%q) failed: %v", tt.name, err)
+		}
+		if d.Name() != tt.expected {
+			t.Errorf("GetDialect(%q): expected %s, got %s", tt.name, tt.expected, d.Name())
+		}
+	}
+
+	if _, err := GetDialect("oracle"); err == nil {
+		t.Error("expected error for unknown dialect")
+	}
+}
+
+func TestDialectSQL(t *testing.T) {
+	pg := PostgresDialect{}
+	lite := SQLiteDialect{}
+
+	if pg.Placeholder(3) != "$3" {
+		t.Errorf("expected $3, got %s", pg.Placeholder(3))
+	}
+	if lite.Placeholder(3) != "?3" {
+		t.Errorf("expected ?3, got %s", lite.Placeholder(3))
+	}
+	if got := lite.LimitOffset(0, 10); got != " LIMIT -1 OFFSET 10" {
+		t.Errorf("expected SQLite offset without limit to use LIMIT -1, got %q", got)
+	}
+	if got := pg.QuoteIdent(`we"ird`); got != `"we""ird"` {
+		t.Errorf("unexpected quoting: %s", got)
+	}
+	if op, _ := pg.Operator("icontains"); op != `%s ILIKE %s ESCAPE '\'` {
+		t.Errorf("unexpected postgres icontains: %s", op)
+	}
+	if op, _ := lite.Operator("icontains"); op != `%s LIKE %s ESCAPE '\'` {
+		t.Errorf("unexpected sqlite icontains: %s", op)
+	}
+	if tmpl, _ := lite.Transform("year"); tmpl != "CAST(strftime('%%Y', %s) AS INTEGER)" {
+		t.Errorf("unexpected sqlite year transform: %s", tmpl)
+	}
+	if _, ok := lite.Transform("week"); ok {
+		t.Error("expected sqlite to have no week transform")
+	}
+	if got := pg.Concat([]string{"a", "$1"}); got != "CONCAT(CAST(a AS TEXT), CAST($1 AS TEXT))" {
+		t.Errorf("unexpected postgres concat: %s", got)
+	}
+	if got := lite.Concat([]string{"a", "?1"}); got != "(COALESCE(a, '') || COALESCE(?1, ''))" {
+		t.Errorf("unexpected sqlite concat: %s", got)
+	}
+	if got := pg.CastParam("$1", pg.DataType("JSONField")); got != "CAST($1 AS JSONB)" {
+		t.Errorf("expected JSON parameters cast to JSONB, got %s", got)
+	}
+	if got := pg.CastParam("$1", ColumnType(pg, "ArrayField", "BIGINT")); got != "CAST($1 AS BIGINT[])" {
+		t.Errorf("expected array parameters cast to their array type, got %s", got)
+	}
+	if got := pg.CastParam("$1", pg.DataType("CharField")); got != "$1" {
+		t.Errorf("expected unresolved parameterized types not to be cast, got %s", got)
+	}
+	if got := lite.CastParam("?1", "TEXT"); got != "?1" {
+		t.Errorf("expected sqlite not to cast, got %s", got)
+	}
+	if !pg.SupportsCursors() || lite.SupportsCursors() {
+		t.Error("expected only postgres to support server-side cursors")
+	}
+	if pg.InsertedFlag() == "" || lite.InsertedFlag() != "" {
+		t.Errorf("expected only postgres to flag inserted rows, got %q and %q", pg.InsertedFlag(), lite.InsertedFlag())
+	}
+	if got := ColumnType(pg, "CharField", 150); got != "VARCHAR(150)" {
+		t.Errorf("expected VARCHAR(150), got %s", got)
+	}
+
+	var nilDB *DB
+	if nilDB.Ops().Name() != "postgres" {
+		t.Error("expected nil DB to fall back to the default dialect")
+	}
+}
+
+func TestSQLiteColumnDefinition(t *testing.T) {
+	database := &DB{ops: SQLiteDialect{}}
+
+	tests := map[string]string{
+		"SERIAL PRIMARY KEY": "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP": "TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+		"JSONB NOT NULL":                           "TEXT NOT NULL",
+		"INTEGER[] NOT NULL":                       "TEXT NOT NULL",
+		"INTEGER NOT NULL REFERENCES go_users(id)": "INTEGER NOT NULL REFERENCES go_users(id)",
+	}
+	for in, want := range tests {
+		if got := database.ColumnDefinition(in); got != want {
+			t.Errorf("ColumnDefinition(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSQLiteIntrospection(t *testing.T) {
+	database, err := NewDB("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.CreateTable("authors", map[string]string{
+		"id":   "SERIAL PRIMARY KEY",
+		"name": "VARCHAR(100) UNIQUE NOT NULL",
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := database.CreateTable("books", map[string]string{
+		"id":        "SERIAL PRIMARY KEY",
+		"title":     "TEXT NOT NULL DEFAULT 'untitled'",
+		"author_id": "INTEGER NOT NULL REFERENCES authors(id)",
+		"rating":    "DOUBLE PRECISION",
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	tables, err := database.GetTables()
+	if err != nil {
+		t.Fatalf("GetTables failed: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Errorf("expected 2 tables, got %v", tables)
+	}
+
+	schema, err := database.GetTableSchema("books")
+	if err != nil {
+		t.Fatalf("GetTableSchema failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"id":        "INTEGER UNIQUE NOT NULL",
+		"title":     "TEXT NOT NULL DEFAULT untitled",
+		"author_id": "INTEGER NOT NULL REFERENCES authors(id)",
+		"rating":    "REAL",
+	}
+	for col, want := range expected {
+		if got := schema.Columns[col]; got != want {
+			t.Errorf("column %s: expected %q, got %q", col, want, got)
+		}
+	}
+
+	authors, _ := database.GetTableSchema("authors")
+	if got := authors.Columns["name"]; got != "VARCHAR(100) UNIQUE NOT NULL" {
+		t.Errorf("expected unique varchar column, got %q", got)
+	}
+
+	missing, err := database.GetTableSchema("missing")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil schema for missing table, got %v, %v", missing, err)
+	}
+}
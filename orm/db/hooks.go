@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// QueryEvent describes a statement executed through a DB
+type QueryEvent struct {
+	SQL      string
+	Args     []interface{}
+	Dialect  string
+	InTx     bool
+	Start    time.Time
+	Duration time.Duration
+	// RowsAffected is -1 for statements that return rows
+	RowsAffected int64
+	Err          error
+}
+
+// QueryHook observes statements. BeforeQuery may return a derived context
+// (e.g. carrying a tracing span) which is passed on to AfterQuery.
+// Duration covers executing the statement, not reading the returned rows.
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// AfterQueryFunc adapts a function into a QueryHook that only observes
+// completed statements
+type AfterQueryFunc func(ctx context.Context, event *QueryEvent)
+
+func (f AfterQueryFunc) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (f AfterQueryFunc) AfterQuery(ctx context.Context, event *QueryEvent) {
+	f(ctx, event)
+}
+
+// hookList is shared by a DB and the transaction-bound copies made from it
+type hookList struct {
+	mu    sync.RWMutex
+	hooks []*QueryHook
+}
+
+func (l *hookList) add(h QueryHook) func() {
+	entry := &h
+	l.mu.Lock()
+	l.hooks = append(l.hooks, entry)
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, e := range l.hooks {
+			if e == entry {
+				l.hooks = append(l.hooks[:i:i], l.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (l *hookList) snapshot(dst []QueryHook) []QueryHook {
+	if l == nil {
+		return dst
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, e := range l.hooks {
+		dst = append(dst, *e)
+	}
+	return dst
+}
+
+// globalHooks run for every DB
+var globalHooks = &hookList{}
+
+// AddQueryHook registers h for statements on every DB.
+// It returns a function that unregisters the hook.
+func AddQueryHook(h QueryHook) (remove func()) {
+	return globalHooks.add(h)
+}
+
+// AddQueryHook registers h for statements on this DB and any transaction
+// started from it. It returns a function that unregisters the hook.
+func (db *DB) AddQueryHook(h QueryHook) (remove func()) {
+	if db.hooks == nil {
+		db.hooks = &hookList{}
+	}
+	return db.hooks.add(h)
+}
+
+// beforeQuery starts an event if any hooks are registered. It returns a nil
+// event otherwise so the common path stays allocation-free.
+func (db *DB) beforeQuery(ctx context.Context, query string, args []interface{}) (context.Context, *QueryEvent, []QueryHook) {
+	hooks := db.hooks.snapshot(globalHooks.snapshot(nil))
+	if len(hooks) == 0 {
+		return ctx, nil, nil
+	}
+
+	event := &QueryEvent{
+		SQL:          query,
+		Args:         args,
+		Dialect:      db.dialect,
+		InTx:         db.tx != nil,
+		Start:        time.Now(),
+		RowsAffected: -1,
+	}
+	for _, h := range hooks {
+		ctx = h.BeforeQuery(ctx, event)
+	}
+	return ctx, event, hooks
+}
+
+func afterQuery(ctx context.Context, event *QueryEvent, hooks []QueryHook, rowsAffected int64, err error) {
+	if event == nil {
+		return
+	}
+	event.Duration = time.Since(event.Start)
+	event.RowsAffected = rowsAffected
+	event.Err = err
+	for _, h := range hooks {
+		h.AfterQuery(ctx, event)
+	}
+}
+
+// SlowQueryLogger logs statements that take at least Threshold
+type SlowQueryLogger struct {
+	Threshold time.Duration
+	// Logger defaults to the standard logger
+	Logger *log.Logger
+}
+
+func (l *SlowQueryLogger) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (l *SlowQueryLogger) AfterQuery(_ context.Context, event *QueryEvent) {
+	if event.Duration < l.Threshold {
+		return
+	}
+	logf := log.Printf
+	if l.Logger != nil {
+		logf = l.Logger.Printf
+	}
+	logf("slow query (%s): %s %v", event.Duration, event.SQL, event.Args)
+}
+
+// CaptureQueries runs fn and returns every statement executed on any DB
+// while it ran. Statements from other goroutines are captured too, so
+// avoid running capturing tests in parallel.
+func CaptureQueries(fn func()) []QueryEvent {
+	var mu sync.Mutex
+	var events []QueryEvent
+	remove := AddQueryHook(AfterQueryFunc(func(_ context.Context, event *QueryEvent) {
+		mu.Lock()
+		events = append(events, *event)
+		mu.Unlock()
+	}))
+	defer remove()
+
+	fn()
+	return events
+}
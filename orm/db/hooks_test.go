@@ -0,0 +1,90 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+type recordingHook struct {
+	before int
+	events []QueryEvent
+}
+
+type hookKey struct{}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	h.before++
+	return context.WithValue(ctx, hookKey{}, event.SQL)
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if ctx.Value(hookKey{}) != event.SQL {
+		panic("context from BeforeQuery was not passed to AfterQuery")
+	}
+	h.events = append(h.events, *event)
+}
+
+func TestQueryHooks(t *testing.T) {
+	database := newTestDB(t)
+	hook := &recordingHook{}
+	remove := database.AddQueryHook(hook)
+
+	database.Exec("INSERT INTO accounts (balance) VALUES (?1), (?2)", 10, 20)
+	database.QueryRow("SELECT SUM(balance) FROM accounts").Scan(new(int))
+	database.Exec("SELECT * FROM missing_table")
+	database.Atomic(context.Background(), func(tx *DB) error {
+		_, err := tx.Exec("UPDATE accounts SET balance = 0")
+		return err
+	})
+
+	if hook.before != 4 || len(hook.events) != 4 {
+		t.Fatalf("expected 4 before/after calls, got %d/%d", hook.before, len(hook.events))
+	}
+	insert := hook.events[0]
+	if insert.RowsAffected != 2 || len(insert.Args) != 2 || insert.Dialect != "sqlite3" {
+		t.Errorf("unexpected insert event: %+v", insert)
+	}
+	if hook.events[1].RowsAffected != -1 {
+		t.Errorf("expected -1 rows affected for a query, got %d", hook.events[1].RowsAffected)
+	}
+	if hook.events[2].Err == nil {
+		t.Error("expected the failing statement to report its error")
+	}
+	if !hook.events[3].InTx || hook.events[3].RowsAffected != 2 {
+		t.Errorf("expected transaction statements to reach DB hooks: %+v", hook.events[3])
+	}
+
+	remove()
+	database.Exec("DELETE FROM accounts")
+	if len(hook.events) != 4 {
+		t.Error("expected no events after the hook was removed")
+	}
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	database := newTestDB(t)
+	var buf bytes.Buffer
+	database.AddQueryHook(&SlowQueryLogger{Logger: log.New(&buf, "", 0)})
+
+	database.Exec("DELETE FROM accounts")
+	if !strings.Contains(buf.String(), "slow query") || !strings.Contains(buf.String(), "DELETE FROM accounts") {
+		t.Errorf("expected slow query to be logged, got %q", buf.String())
+	}
+}
+
+func TestCaptureQueries(t *testing.T) {
+	database := newTestDB(t)
+	events := CaptureQueries(func() {
+		database.Exec("INSERT INTO accounts (balance) VALUES (1)")
+		countRows(t, database)
+	})
+	if len(events) != 2 {
+		t.Fatalf("expected 2 captured queries, got %d", len(events))
+	}
+	if after := CaptureQueries(func() {}); len(after) != 0 {
+		t.Errorf("expected an empty capture, got %d", len(after))
+	}
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PostgresDialect implements Dialect for PostgreSQL (lib/pq)
+type PostgresDialect struct{}
+
+var postgresOperators = map[string]string{
+	"exact":       "%s = %s",
+	"iexact":      "%s ILIKE %s ESCAPE '\\'",
+	"ne":          "%s <> %s",
+	"contains":    "%s LIKE %s ESCAPE '\\'",
+	"icontains":   "%s ILIKE %s ESCAPE '\\'",
+	"startswith":  "%s LIKE %s ESCAPE '\\'",
+	"istartswith": "%s ILIKE %s ESCAPE '\\'",
+	"endswith":    "%s LIKE %s ESCAPE '\\'",
+	"iendswith":   "%s ILIKE %s ESCAPE '\\'",
+	"regex":       "%s ~ %s",
+	"iregex":      "%s ~* %s",
+	"gt":          "%s > %s",
+	"gte":         "%s >= %s",
+	"lt":          "%s < %s",
+	"lte":         "%s <= %s",
+}
+
+var postgresTransforms = map[string]string{
+	"year":     "EXTRACT(YEAR FROM %s)",
+	"quarter":  "EXTRACT(QUARTER FROM %s)",
+	"month":    "EXTRACT(MONTH FROM %s)",
+	"week":     "EXTRACT(WEEK FROM %s)",
+	"day":      "EXTRACT(DAY FROM %s)",
+	"week_day": "(EXTRACT(DOW FROM %s) + 1)",
+	"hour":     "EXTRACT(HOUR FROM %s)",
+	"minute":   "EXTRACT(MINUTE FROM %s)",
+	"second":   "EXTRACT(SECOND FROM %s)",
+	"date":     "CAST(%s AS DATE)",
+	"time":     "CAST(%s AS TIME)",
+}
+
+var postgresDataTypes = map[string]string{
+	"AutoField":         "SERIAL PRIMARY KEY",
+	"BooleanField":      "BOOLEAN",
+	"SmallIntegerField": "SMALLINT",
+	"IntegerField":      "INTEGER",
+	"BigIntegerField":   "BIGINT",
+	"FloatField":        "DOUBLE PRECISION",
+	"CharField":         "VARCHAR(%v)",
+	"TextField":         "TEXT",
+	"BinaryField":       "BYTEA",
+	"JSONField":         "JSONB",
+	"DateTimeField":     "TIMESTAMP WITH TIME ZONE",
+	"ArrayField":        "%v[]",
+}
+
+func (PostgresDialect) Name() string       { return "postgres" }
+func (PostgresDialect) DriverName() string { return "postgres" }
+
+func (PostgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (PostgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (PostgresDialect) Operator(lookup string) (string, bool) {
+	op, ok := postgresOperators[lookup]
+	return op, ok
+}
+
+func (PostgresDialect) Transform(name string) (string, bool) {
+	t, ok := postgresTransforms[name]
+	return t, ok
+}
+
+// Concat uses CONCAT, which already skips NULL arguments. Each argument is
+// cast to TEXT, since CONCAT is variadic over "any" and Postgres can't infer
+// the type of a bare bind parameter from it.
+func (PostgresDialect) Concat(args []string) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("CAST(%s AS TEXT)", arg)
+	}
+	return fmt.Sprintf("CONCAT(%s)", strings.Join(parts, ", "))
+}
+
+func (PostgresDialect) DataType(internalType string) string {
+	return postgresDataTypes[internalType]
+}
+
+func (PostgresDialect) SupportsReturning() bool { return true }
+
+// InsertedFlag relies on xmax being 0 only for a freshly inserted row version
+func (PostgresDialect) InsertedFlag() string { return "(xmax = 0)" }
+
+func (PostgresDialect) SupportsCopy() bool { return true }
+
+func (PostgresDialect) SupportsCursors() bool { return true }
+
+func (PostgresDialect) ValuesColumnAliases() bool { return true }
+
+// CastParam casts parameters, which PostgreSQL otherwise types as text in
+// VALUES lists, failing on comparisons and assignments to other types
+func (PostgresDialect) CastParam(placeholder, columnType string) string {
+	if columnType == "" || strings.Contains(columnType, "%") {
+		return placeholder
+	}
+	return fmt.Sprintf("CAST(%s AS %s)", placeholder, columnType)
+}
+
+func (PostgresDialect) ForUpdate(of []string, noWait, skipLocked bool) string {
+	clause := " FOR UPDATE"
+	if len(of) > 0 {
+		clause += " OF " + strings.Join(of, ", ")
+	}
+	switch {
+	case noWait:
+		clause += " NOWAIT"
+	case skipLocked:
+		clause += " SKIP LOCKED"
+	}
+	return clause
+}
+
+func (PostgresDialect) LimitOffset(limit, offset int) string {
+	var clause string
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return clause
+}
+
+// TableSchema returns the current schema of a table from information_schema
+func (PostgresDialect) TableSchema(database *DB, tableName string) (*TableInfo, error) {
+	// Query to get columns, types, nullability, unique constraints, and max length
+	query := `
+		SELECT
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			c.column_default,
+			c.character_maximum_length,
+			c.udt_name,
+			EXISTS (
+				SELECT 1
+				FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name
+				WHERE tc.table_name = c.table_name
+				  AND kcu.column_name = c.column_name
+				  AND (tc.constraint_type = 'UNIQUE' OR tc.constraint_type = 'PRIMARY KEY')
+			) as is_unique
+		FROM information_schema.columns c
+		WHERE c.table_name = $1
+	`
+	rows, err := database.Query(query, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := make(map[string]string)
+	for rows.Next() {
+		var name, dtype, nullable string
+		var isUnique bool
+		var colDefault, maxLen, udtName *string
+		if err := rows.Scan(&name, &dtype, &nullable, &colDefault, &maxLen, &udtName, &isUnique); err != nil {
+			return nil, err
+		}
+
+		// Normalize type to match our collectFields output for comparison
+		normType := strings.ToUpper(dtype)
+
+		// Postgres specific normalizations
+		switch dtype {
+		case "integer":
+			normType = "INTEGER"
+		case "bigint":
+			normType = "BIGINT"
+		case "smallint":
+			normType = "SMALLINT"
+		case "boolean":
+			normType = "BOOLEAN"
+		case "text":
+			normType = "TEXT"
+		case "character varying":
+			if maxLen != nil {
+				normType = fmt.Sprintf("VARCHAR(%s)", *maxLen)
+			} else {
+				normType = "VARCHAR"
+			}
+		case "double precision":
+			normType = "DOUBLE PRECISION"
+		case "timestamp with time zone":
+			normType = "TIMESTAMP WITH TIME ZONE"
+		case "ARRAY":
+			if udtName != nil {
+				switch *udtName {
+				case "_int4":
+					normType = "INTEGER[]"
+				case "_int8":
+					normType = "BIGINT[]"
+				case "_text":
+					normType = "TEXT[]"
+				case "_float8":
+					normType = "DOUBLE PRECISION[]"
+				case "_bool":
+					normType = "BOOLEAN[]"
+				default:
+					normType = strings.TrimPrefix(*udtName, "_") + "[]"
+				}
+			}
+		default:
+			// Fallback to udtName for types non-standard in information_schema.columns
+			if udtName != nil {
+				u := strings.ToUpper(*udtName)
+				if u == "JSONB" || u == "UUID" || u == "INET" || u == "TSVECTOR" || u == "INTERVAL" || u == "DATE" || u == "TIME" || u == "NUMERIC" || u == "BYTEA" || u == "HSTORE" {
+					normType = u
+				}
+			}
+		}
+
+		if isUnique {
+			normType += " UNIQUE"
+		}
+		if nullable == "NO" {
+			normType += " NOT NULL"
+		}
+		if colDefault != nil {
+			// Clean up default: e.g. "'active'::text" -> "active", or "true" -> "true"
+			d := *colDefault
+			d = strings.Split(d, "::")[0]
+			d = strings.Trim(d, "'")
+
+			// Only append if it's not a sequence (like nextval)
+			if !strings.Contains(d, "nextval") {
+				normType += " DEFAULT " + d
+			}
+		}
+
+		cols[name] = normType
+	}
+
+	if len(cols) == 0 {
+		return nil, nil // Table doesn't exist
+	}
+
+	return &TableInfo{Name: tableName, Columns: cols}, nil
+}
+
+// Tables returns a list of all user tables in the public schema
+func (PostgresDialect) Tables(database *DB) ([]string, error) {
+	query := `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+	`
+	rows, err := database.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, nil
+}
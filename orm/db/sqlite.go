@@ -0,0 +1,333 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDialect implements Dialect for SQLite (mattn/go-sqlite3).
+// LIKE is case-insensitive for ASCII in SQLite, so contains/icontains and
+// exact/iexact only differ for non-ASCII text.
+type SQLiteDialect struct{}
+
+var sqliteOperators = map[string]string{
+	"exact":       "%s = %s",
+	"iexact":      "%s LIKE %s ESCAPE '\\'",
+	"ne":          "%s <> %s",
+	"contains":    "%s LIKE %s ESCAPE '\\'",
+	"icontains":   "%s LIKE %s ESCAPE '\\'",
+	"startswith":  "%s LIKE %s ESCAPE '\\'",
+	"istartswith": "%s LIKE %s ESCAPE '\\'",
+	"endswith":    "%s LIKE %s ESCAPE '\\'",
+	"iendswith":   "%s LIKE %s ESCAPE '\\'",
+	"regex":       "%s REGEXP %s",
+	"iregex":      "%s REGEXP '(?i)' || %s",
+	"gt":          "%s > %s",
+	"gte":         "%s >= %s",
+	"lt":          "%s < %s",
+	"lte":         "%s <= %s",
+}
+
+// sqliteTransforms read dates with strftime, which returns text, so numeric
+// parts are cast to compare with integers. week_day counts from Sunday = 1.
+var sqliteTransforms = map[string]string{
+	"year":     "CAST(strftime('%%Y', %s) AS INTEGER)",
+	"quarter":  "((CAST(strftime('%%m', %s) AS INTEGER) + 2) / 3)",
+	"month":    "CAST(strftime('%%m', %s) AS INTEGER)",
+	"day":      "CAST(strftime('%%d', %s) AS INTEGER)",
+	"week_day": "(CAST(strftime('%%w', %s) AS INTEGER) + 1)",
+	"hour":     "CAST(strftime('%%H', %s) AS INTEGER)",
+	"minute":   "CAST(strftime('%%M', %s) AS INTEGER)",
+	"second":   "CAST(strftime('%%S', %s) AS INTEGER)",
+	"date":     "date(%s)",
+	"time":     "time(%s)",
+}
+
+var sqliteDataTypes = map[string]string{
+	"AutoField":         "INTEGER PRIMARY KEY AUTOINCREMENT",
+	"BooleanField":      "BOOLEAN",
+	"SmallIntegerField": "SMALLINT",
+	"IntegerField":      "INTEGER",
+	"BigIntegerField":   "BIGINT",
+	"FloatField":        "REAL",
+	"CharField":         "VARCHAR(%v)",
+	"TextField":         "TEXT",
+	"BinaryField":       "BLOB",
+	"JSONField":         "TEXT",
+	"DateTimeField":     "TIMESTAMP",
+	"ArrayField":        "TEXT",
+}
+
+func (SQLiteDialect) Name() string { return "sqlite3" }
+
+// DriverName is mattn/go-sqlite3 with a REGEXP function, which SQLite
+// declares but leaves to the application
+func (SQLiteDialect) DriverName() string { return "sqlite3_regexp" }
+
+func init() {
+	sql.Register("sqlite3_regexp", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqliteRegexp, true)
+		},
+	})
+}
+
+// sqliteRegexp implements "value REGEXP pattern"; NULL never matches
+func sqliteRegexp(pattern string, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case []byte:
+		return regexp.Match(pattern, v)
+	default:
+		return regexp.MatchString(pattern, fmt.Sprint(v))
+	}
+}
+
+func (SQLiteDialect) Placeholder(n int) string {
+	return fmt.Sprintf("?%d", n)
+}
+
+func (SQLiteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (SQLiteDialect) Operator(lookup string) (string, bool) {
+	op, ok := sqliteOperators[lookup]
+	return op, ok
+}
+
+func (SQLiteDialect) Transform(name string) (string, bool) {
+	t, ok := sqliteTransforms[name]
+	return t, ok
+}
+
+// Concat uses ||, which yields NULL if any argument is NULL
+func (SQLiteDialect) Concat(args []string) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("COALESCE(%s, '')", arg)
+	}
+	return "(" + strings.Join(parts, " || ") + ")"
+}
+
+func (SQLiteDialect) DataType(internalType string) string {
+	return sqliteDataTypes[internalType]
+}
+
+// SupportsReturning is false so inserts work on SQLite builds older than 3.35
+func (SQLiteDialect) SupportsReturning() bool { return false }
+
+func (SQLiteDialect) InsertedFlag() string { return "" }
+
+func (SQLiteDialect) SupportsCopy() bool { return false }
+
+func (SQLiteDialect) SupportsCursors() bool { return false }
+
+func (SQLiteDialect) ValuesColumnAliases() bool { return false }
+
+// CastParam leaves parameters as they are: SQLite columns take values of
+// any type
+func (SQLiteDialect) CastParam(placeholder, columnType string) string { return placeholder }
+
+// ForUpdate returns "": SQLite has no row locks, and a write transaction
+// already locks the whole database
+func (SQLiteDialect) ForUpdate(of []string, noWait, skipLocked bool) string { return "" }
+
+func (SQLiteDialect) LimitOffset(limit, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	case limit > 0:
+		return fmt.Sprintf(" LIMIT %d", limit)
+	case offset > 0:
+		// SQLite does not accept OFFSET without LIMIT
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d", offset)
+	}
+	return ""
+}
+
+// sqliteColumnReplacer rewrites Postgres column definitions, as found in
+// migration files generated against Postgres, into SQLite equivalents
+var sqliteColumnReplacer = strings.NewReplacer(
+	"SERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT",
+	"TIMESTAMP WITH TIME ZONE", "TIMESTAMP",
+	"DOUBLE PRECISION", "REAL",
+	"JSONB", "TEXT",
+	"BYTEA", "BLOB",
+)
+
+var sqliteArrayType = regexp.MustCompile(`\b[A-Z]+\[\]`)
+
+func (SQLiteDialect) translateColumn(def string) string {
+	def = sqliteColumnReplacer.Replace(def)
+	return sqliteArrayType.ReplaceAllString(def, "TEXT")
+}
+
+// configure limits in-memory databases to a single connection, since every
+// new connection to ":memory:" would otherwise see an empty database
+func (SQLiteDialect) configure(conn *sql.DB, dsn string) {
+	if strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory") {
+		conn.SetMaxOpenConns(1)
+	}
+}
+
+// TableSchema introspects a table via PRAGMA table_info, index_list and foreign_key_list
+func (d SQLiteDialect) TableSchema(database *DB, tableName string) (*TableInfo, error) {
+	rows, err := database.Query(fmt.Sprintf("PRAGMA table_info(%s)", d.QuoteIdent(tableName)))
+	if err != nil {
+		return nil, err
+	}
+
+	type column struct {
+		name, dtype string
+		notNull     bool
+		dflt        *string
+		pk          int
+	}
+	var columns []column
+	for rows.Next() {
+		var c column
+		var cid int
+		if err := rows.Scan(&cid, &c.name, &c.dtype, &c.notNull, &c.dflt, &c.pk); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	rows.Close()
+
+	if len(columns) == 0 {
+		return nil, nil // Table doesn't exist
+	}
+
+	unique, err := d.uniqueColumns(database, tableName)
+	if err != nil {
+		return nil, err
+	}
+	references, err := d.foreignKeys(database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := make(map[string]string)
+	for _, c := range columns {
+		// Primary keys normalize the same way the autodetector normalizes AutoField
+		if c.pk > 0 {
+			cols[c.name] = "INTEGER UNIQUE NOT NULL"
+			continue
+		}
+
+		normType := strings.ToUpper(c.dtype)
+		if unique[c.name] {
+			normType += " UNIQUE"
+		}
+		if c.notNull {
+			normType += " NOT NULL"
+		}
+		if c.dflt != nil {
+			normType += " DEFAULT " + strings.Trim(*c.dflt, "'")
+		}
+		if ref, ok := references[c.name]; ok {
+			normType += " REFERENCES " + ref
+		}
+		cols[c.name] = normType
+	}
+
+	return &TableInfo{Name: tableName, Columns: cols}, nil
+}
+
+// uniqueColumns returns columns covered by a single-column unique index
+func (d SQLiteDialect) uniqueColumns(database *DB, tableName string) (map[string]bool, error) {
+	rows, err := database.Query(fmt.Sprintf("PRAGMA index_list(%s)", d.QuoteIdent(tableName)))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []string
+	for rows.Next() {
+		var seq, isUnique, partial int
+		var name, origin string
+		if err := rows.Scan(&seq, &name, &isUnique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if isUnique == 1 && origin != "pk" {
+			indexes = append(indexes, name)
+		}
+	}
+	rows.Close()
+
+	unique := make(map[string]bool)
+	for _, idx := range indexes {
+		infoRows, err := database.Query(fmt.Sprintf("PRAGMA index_info(%s)", d.QuoteIdent(idx)))
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for infoRows.Next() {
+			var seqno, cid int
+			var name string
+			if err := infoRows.Scan(&seqno, &cid, &name); err != nil {
+				infoRows.Close()
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		infoRows.Close()
+
+		if len(names) == 1 {
+			unique[names[0]] = true
+		}
+	}
+	return unique, nil
+}
+
+// foreignKeys returns "table(column)" references keyed by local column
+func (d SQLiteDialect) foreignKeys(database *DB, tableName string) (map[string]string, error) {
+	rows, err := database.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s)", d.QuoteIdent(tableName)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[string]string)
+	for rows.Next() {
+		var id, seq int
+		var table, from, onUpdate, onDelete, match string
+		var to *string
+		if err := rows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		target := "id"
+		if to != nil {
+			target = *to
+		}
+		refs[from] = fmt.Sprintf("%s(%s)", table, target)
+	}
+	return refs, nil
+}
+
+// Tables returns all user tables, skipping SQLite's internal tables
+func (SQLiteDialect) Tables(database *DB) ([]string, error) {
+	rows, err := database.Query(
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, nil
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotInTransaction is returned by operations that require an active transaction
+var ErrNotInTransaction = errors.New("not in a transaction")
+
+// txState is shared by every DB handle bound to the same transaction
+type txState struct {
+	tx         *sql.Tx
+	savepoints int
+	onCommit   []func()
+	mu         sync.Mutex
+}
+
+// Atomic runs fn inside a transaction and commits if it returns nil.
+// If fn returns an error or panics, the transaction is rolled back.
+//
+// The tx passed to fn is a *DB bound to the transaction, so querysets created
+// with it (or bound via QuerySet.WithTx) run inside the transaction. Calling
+// Atomic on a transaction-bound DB nests using a SAVEPOINT: an error rolls
+// back to the savepoint and leaves the outer transaction usable.
+func (db *DB) Atomic(ctx context.Context, fn func(tx *DB) error) (err error) {
+	if db.tx != nil {
+		return db.atomicSavepoint(ctx, fn)
+	}
+
+	sqlTx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	state := &txState{tx: sqlTx}
+	txDB := &DB{conn: db.conn, tx: state, dialect: db.dialect, ops: db.ops, dsn: db.dsn, timeout: db.timeout, hooks: db.hooks}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(txDB); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback failed: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Callbacks only run once the outermost transaction is durable
+	state.mu.Lock()
+	callbacks := state.onCommit
+	state.onCommit = nil
+	state.mu.Unlock()
+	for _, cb := range callbacks {
+		cb()
+	}
+	return nil
+}
+
+func (db *DB) atomicSavepoint(ctx context.Context, fn func(tx *DB) error) (err error) {
+	state := db.tx
+
+	state.mu.Lock()
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+	pending := len(state.onCommit)
+	state.mu.Unlock()
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	rollback := func() error {
+		// Callbacks registered inside a rolled-back savepoint must never fire
+		state.mu.Lock()
+		state.onCommit = state.onCommit[:pending]
+		state.mu.Unlock()
+		_, err := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(db); err != nil {
+		if rbErr := rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback to savepoint failed: %w", rbErr))
+		}
+		return err
+	}
+
+	if _, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// InTransaction reports whether this DB is bound to an active transaction
+func (db *DB) InTransaction() bool {
+	return db != nil && db.tx != nil
+}
+
+// OnCommit registers fn to run after the outermost transaction commits.
+// Outside a transaction fn runs immediately. Callbacks registered inside a
+// savepoint that is rolled back, or a transaction that is rolled back, are discarded.
+func (db *DB) OnCommit(fn func()) {
+	if db.tx == nil {
+		fn()
+		return
+	}
+	db.tx.mu.Lock()
+	defer db.tx.mu.Unlock()
+	db.tx.onCommit = append(db.tx.onCommit, fn)
+}
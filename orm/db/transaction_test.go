@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	database, err := NewDB("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := database.CreateTable("accounts", map[string]string{
+		"id":      "SERIAL PRIMARY KEY",
+		"balance": "INTEGER NOT NULL",
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	return database
+}
+
+func countRows(t *testing.T, database *DB) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&n); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	return n
+}
+
+func TestAtomic(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commits on success", func(t *testing.T) {
+		database := newTestDB(t)
+		err := database.Atomic(ctx, func(tx *DB) error {
+			if !tx.InTransaction() {
+				t.Error("expected tx to report an active transaction")
+			}
+			_, err := tx.Exec("INSERT INTO accounts (balance) VALUES (100)")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Atomic failed: %v", err)
+		}
+		if database.InTransaction() {
+			t.Error("outer DB must not be bound to the transaction")
+		}
+		if n := countRows(t, database); n != 1 {
+			t.Errorf("expected 1 row, got %d", n)
+		}
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		database := newTestDB(t)
+		boom := errors.New("boom")
+		err := database.Atomic(ctx, func(tx *DB) error {
+			if _, err := tx.Exec("INSERT INTO accounts (balance) VALUES (100)"); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+		if n := countRows(t, database); n != 0 {
+			t.Errorf("expected rollback, got %d rows", n)
+		}
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		database := newTestDB(t)
+		func() {
+			defer func() { recover() }()
+			database.Atomic(ctx, func(tx *DB) error {
+				tx.Exec("INSERT INTO accounts (balance) VALUES (100)")
+				panic("boom")
+			})
+		}()
+		if n := countRows(t, database); n != 0 {
+			t.Errorf("expected rollback after panic, got %d rows", n)
+		}
+	})
+
+	t.Run("nested savepoint rollback keeps outer work", func(t *testing.T) {
+		database := newTestDB(t)
+		err := database.Atomic(ctx, func(tx *DB) error {
+			if _, err := tx.Exec("INSERT INTO accounts (balance) VALUES (1)"); err != nil {
+				return err
+			}
+			inner := tx.Atomic(ctx, func(tx *DB) error {
+				tx.Exec("INSERT INTO accounts (balance) VALUES (2)")
+				return errors.New("inner failure")
+			})
+			if inner == nil {
+				t.Error("expected inner error")
+			}
+			return tx.Atomic(ctx, func(tx *DB) error {
+				_, err := tx.Exec("INSERT INTO accounts (balance) VALUES (3)")
+				return err
+			})
+		})
+		if err != nil {
+			t.Fatalf("Atomic failed: %v", err)
+		}
+
+		var sum int
+		database.QueryRow("SELECT SUM(balance) FROM accounts").Scan(&sum)
+		if sum != 4 {
+			t.Errorf("expected rows 1 and 3 to be committed (sum 4), got %d", sum)
+		}
+	})
+}
+
+func TestOnCommit(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+
+	var fired []string
+	database.OnCommit(func() { fired = append(fired, "immediate") })
+	if len(fired) != 1 {
+		t.Fatal("expected OnCommit outside a transaction to run immediately")
+	}
+	fired = nil
+
+	err := database.Atomic(ctx, func(tx *DB) error {
+		tx.OnCommit(func() { fired = append(fired, "outer") })
+		tx.Atomic(ctx, func(tx *DB) error {
+			tx.OnCommit(func() { fired = append(fired, "discarded") })
+			return errors.New("rollback savepoint")
+		})
+		tx.Atomic(ctx, func(tx *DB) error {
+			tx.OnCommit(func() { fired = append(fired, "inner") })
+			return nil
+		})
+		if len(fired) != 0 {
+			t.Error("callbacks must not fire before the outermost commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Atomic failed: %v", err)
+	}
+	if len(fired) != 2 || fired[0] != "outer" || fired[1] != "inner" {
+		t.Errorf("unexpected callbacks: %v", fired)
+	}
+
+	fired = nil
+	database.Atomic(ctx, func(tx *DB) error {
+		tx.OnCommit(func() { fired = append(fired, "never") })
+		return errors.New("rollback")
+	})
+	if len(fired) != 0 {
+		t.Errorf("callbacks must not fire after rollback, got %v", fired)
+	}
+}
+
+// slowQuery burns CPU in SQLite long enough to trip a short timeout
+const slowQuery = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000) SELECT COUNT(*) FROM c`
+
+func TestContextCancellation(t *testing.T) {
+	database := newTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := database.ExecContext(ctx, "INSERT INTO accounts (balance) VALUES (1)"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	database.SetStatementTimeout(20 * time.Millisecond)
+	var n int
+	err := database.QueryRowContext(context.Background(), slowQuery).Scan(&n)
+	if err == nil {
+		t.Fatal("expected the default statement timeout to interrupt the query")
+	}
+
+	// An explicit deadline on the context wins over the default
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM accounts").Scan(&n); err != nil {
+		t.Errorf("unexpected error with explicit deadline: %v", err)
+	}
+}
+
+func TestStatementTimeoutReleased(t *testing.T) {
+	database := newTestDB(t)
+	database.SetStatementTimeout(time.Minute)
+
+	var statement context.Context
+	database.AddQueryHook(AfterQueryFunc(func(ctx context.Context, _ *QueryEvent) { statement = ctx }))
+
+	rows, err := database.Query("SELECT id FROM accounts")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if statement.Err() != nil {
+		t.Fatal("expected the timeout to bound reading the rows")
+	}
+	rows.Close()
+	if statement.Err() == nil {
+		t.Error("expected closing the rows to release the statement timeout")
+	}
+
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&n); err != nil {
+		t.Fatalf("QueryRow failed: %v", err)
+	}
+	if statement.Err() == nil {
+		t.Error("expected scanning the row to release the statement timeout")
+	}
+}
@@ -11,10 +11,19 @@ import (
 func (db *DB) CreateTable(tableName string, fields map[string]string) error {
 	var cols []string
 	for name, typ := range fields {
-		cols = append(cols, fmt.Sprintf("%s %s", name, typ))
+		cols = append(cols, fmt.Sprintf("%s %s", name, db.ColumnDefinition(typ)))
 	}
 
 	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableName, strings.Join(cols, ", "))
 	_, err := db.Exec(query)
 	return err
 }
+
+// ColumnDefinition adapts a column definition written for Postgres (the format
+// used by generated migrations) to this connection's dialect
+func (db *DB) ColumnDefinition(def string) string {
+	if t, ok := db.Ops().(interface{ translateColumn(string) string }); ok {
+		return t.translateColumn(def)
+	}
+	return def
+}
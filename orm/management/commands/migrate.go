@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	coremanagement "github.com/anuragcarret/djang-drf-go/core/management"
+	"github.com/anuragcarret/djang-drf-go/orm/connections"
 	"github.com/anuragcarret/djang-drf-go/orm/db"
 	"github.com/anuragcarret/djang-drf-go/orm/migrations"
 )
@@ -12,6 +14,13 @@ type MigrateCommand struct {
 	database *db.DB
 }
 
+type migrateFlags struct {
+	Database string `flag:"database" default:"default" help:"Database alias to migrate"`
+}
+
+// NewMigrateCommand migrates database, or with a nil database the alias
+// named by --database from the connections package, applying only the
+// operations its routers allow.
 func NewMigrateCommand(database *db.DB) *MigrateCommand {
 	return &MigrateCommand{database: database}
 }
@@ -21,6 +30,17 @@ func (c *MigrateCommand) Help() string { return "Apply database migrations" }
 
 func (c *MigrateCommand) Run(ctx context.Context, args []string) error {
 	executor := migrations.NewExecutor(c.database)
+	if c.database == nil {
+		var opts migrateFlags
+		if err := coremanagement.BindFlags(&opts).Parse(args); err != nil {
+			return err
+		}
+		database, err := connections.Get(opts.Database)
+		if err != nil {
+			return err
+		}
+		executor = migrations.NewExecutor(database).Route(opts.Database, connections.Connections.AllowMigrate)
+	}
 	allMigrations := migrations.GlobalRegistry.All()
 
 	fmt.Printf("Operations to perform:\n")
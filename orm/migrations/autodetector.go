@@ -18,6 +18,11 @@ func NewAutodetector(database *db.DB) *Autodetector {
 	return &Autodetector{db: database}
 }
 
+// ops returns the dialect used for column types (Postgres when no DB is set)
+func (a *Autodetector) ops() db.Dialect {
+	return a.db.Ops()
+}
+
 // Changes detects missing tables and columns
 func (a *Autodetector) Changes() ([]Operation, error) {
 	var ops []Operation
@@ -84,7 +89,7 @@ func (a *Autodetector) detectM2MChanges(tableName string, model interface{}, dbT
 			// In real Django, it's 'to=othermodel'
 
 			fields := map[string]string{
-				"id":    "SERIAL PRIMARY KEY",
+				"id":    a.ops().DataType("AutoField"),
 				toCol:   "INTEGER NOT NULL",
 				fromCol: "INTEGER NOT NULL",
 			}
@@ -124,7 +129,7 @@ func (a *Autodetector) collectFields(t reflect.Type, fields map[string]string) {
 		if tag == "" {
 			tag = f.Tag.Get("json")
 		}
-		if tag == "-" || tag == "" || hasOption(tag, "m2m") || hasOption(tag, "relation") {
+		if tag == "-" || tag == "" || hasOption(tag, "m2m") || hasOption(tag, "relation") || hasOption(tag, "annotation") {
 			continue
 		}
 
@@ -136,50 +141,51 @@ func (a *Autodetector) collectFields(t reflect.Type, fields map[string]string) {
 		}
 
 		// Type mapping
-		dbType := "TEXT"
+		d := a.ops()
+		dbType := d.DataType("TextField")
 		maxLength := getOptionValue(tag, "max_length")
 		explicitType := getOptionValue(tag, "type")
 
 		switch f.Type.Kind() {
 		case reflect.Bool:
-			dbType = "BOOLEAN"
+			dbType = d.DataType("BooleanField")
 		case reflect.Int16, reflect.Uint16:
-			dbType = "SMALLINT"
+			dbType = d.DataType("SmallIntegerField")
 		case reflect.Int32, reflect.Uint32, reflect.Int:
-			dbType = "INTEGER"
+			dbType = d.DataType("IntegerField")
 		case reflect.Int64, reflect.Uint64:
-			dbType = "BIGINT"
+			dbType = d.DataType("BigIntegerField")
 		case reflect.Float32, reflect.Float64:
-			dbType = "DOUBLE PRECISION"
+			dbType = d.DataType("FloatField")
 		case reflect.String:
 			if maxLength != "" {
-				dbType = fmt.Sprintf("VARCHAR(%s)", maxLength)
+				dbType = db.ColumnType(d, "CharField", maxLength)
 			} else {
-				dbType = "TEXT"
+				dbType = d.DataType("TextField")
 			}
 		case reflect.Slice:
 			if f.Type.Elem().Kind() == reflect.Uint8 {
-				dbType = "BYTEA"
+				dbType = d.DataType("BinaryField")
 			} else {
 				// Simple array support: map element type and append []
-				elemType := "TEXT"
+				elemType := d.DataType("TextField")
 				switch f.Type.Elem().Kind() {
 				case reflect.Int32, reflect.Int:
-					elemType = "INTEGER"
+					elemType = d.DataType("IntegerField")
 				case reflect.Int64:
-					elemType = "BIGINT"
+					elemType = d.DataType("BigIntegerField")
 				case reflect.Float64:
-					elemType = "DOUBLE PRECISION"
+					elemType = d.DataType("FloatField")
 				case reflect.Bool:
-					elemType = "BOOLEAN"
+					elemType = d.DataType("BooleanField")
 				}
-				dbType = elemType + "[]"
+				dbType = db.ColumnType(d, "ArrayField", elemType)
 			}
 		case reflect.Map, reflect.Struct:
 			if f.Type.String() != "time.Time" && f.Type.String() != "*time.Time" {
-				dbType = "JSONB"
+				dbType = d.DataType("JSONField")
 			} else {
-				dbType = "TIMESTAMP WITH TIME ZONE"
+				dbType = d.DataType("DateTimeField")
 			}
 		}
 
@@ -197,7 +203,7 @@ func (a *Autodetector) collectFields(t reflect.Type, fields map[string]string) {
 		o2o := getOptionValue(tag, "one_to_one")
 
 		if isPK {
-			dbType = "SERIAL PRIMARY KEY"
+			dbType = d.DataType("AutoField")
 		} else {
 			if fk != "" || o2o != "" {
 				rel := fk
@@ -247,7 +253,7 @@ func (a *Autodetector) collectFields(t reflect.Type, fields map[string]string) {
 }
 
 func isOption(s string) bool {
-	options := []string{"null", "unique", "primary_key", "index", "auto_increment", "blank", "default", "foreign_key", "m2m"}
+	options := []string{"null", "unique", "primary_key", "index", "auto_increment", "blank", "default", "foreign_key", "m2m", "annotation"}
 	for _, opt := range options {
 		if s == opt {
 			return true
@@ -335,16 +341,17 @@ func (a *Autodetector) isFieldEqual(modelType, dbType string) bool {
 	}
 
 	// Semantic normalization
-	// 1. SERIAL PRIMARY KEY -> INTEGER UNIQUE NOT NULL
-	mNorm := normalizeType(modelType)
-	dNorm := normalizeType(dbType)
+	// 1. AutoField (e.g. SERIAL PRIMARY KEY) -> INTEGER UNIQUE NOT NULL
+	autoField := a.ops().DataType("AutoField")
+	mNorm := normalizeType(modelType, autoField)
+	dNorm := normalizeType(dbType, autoField)
 
 	return mNorm == dNorm
 }
 
-func normalizeType(t string) string {
+func normalizeType(t, autoField string) string {
 	res := t
-	res = strings.ReplaceAll(res, "SERIAL PRIMARY KEY", "INTEGER UNIQUE NOT NULL")
+	res = strings.ReplaceAll(res, autoField, "INTEGER UNIQUE NOT NULL")
 	// Add more normalizations if needed
 	return res
 }
@@ -2,6 +2,8 @@ package migrations
 
 import (
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -73,6 +75,13 @@ func TestCollectFields(t *testing.T) {
 				"related_id": "INTEGER UNIQUE NOT NULL REFERENCES related_model(id)",
 			},
 		},
+		{
+			name:  "Annotation fields are not columns",
+			model: &AnnotatedModel{},
+			expected: map[string]string{
+				"id": "SERIAL PRIMARY KEY",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,6 +105,13 @@ func TestCollectFields(t *testing.T) {
 	}
 }
 
+type AnnotatedModel struct {
+	ID        uint64 `drf:"id;primary_key"`
+	BookCount int64  `drf:"book_count;annotation"`
+}
+
+func (m *AnnotatedModel) TableName() string { return "annotated_model" }
+
 type M2MModel struct {
 	ID        uint64     `drf:"id;primary_key"`
 	Followers []M2MModel `drf:"m2m=test_m2m_through;to=to_id;from=from_id"`
@@ -274,3 +290,75 @@ func TestDetectRemovedField(t *testing.T) {
 		t.Errorf("Expected RemoveField operation for 'old_field', but not found in %v", ops)
 	}
 }
+
+func TestSQLiteAutodetectorRoundTrip(t *testing.T) {
+	database, err := db.NewDB("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer database.Close()
+
+	detector := NewAutodetector(database)
+
+	fields := make(map[string]string)
+	detector.collectFields(reflect.TypeOf(TestModel{}), fields)
+	if fields["id"] != "INTEGER PRIMARY KEY AUTOINCREMENT" {
+		t.Errorf("expected SQLite auto field, got %q", fields["id"])
+	}
+
+	if err := (&CreateTable{Name: "related_model", Fields: map[string]string{"id": "SERIAL PRIMARY KEY"}}).Apply(database); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	for _, model := range []interface{}{&TestModel{}, &FKModel{}} {
+		op := detector.createTableOp(model.(interface{ TableName() string }).TableName(), model)
+		if err := op.Apply(database); err != nil {
+			t.Fatalf("%s failed: %v", op.Describe(), err)
+		}
+	}
+
+	// A freshly created table must introspect back without pending changes
+	for _, model := range []interface{}{&TestModel{}, &FKModel{}} {
+		ops, err := detector.detectColumnChanges(model.(interface{ TableName() string }).TableName(), model)
+		if err != nil {
+			t.Fatalf("detectColumnChanges failed: %v", err)
+		}
+		for _, op := range ops {
+			t.Errorf("unexpected operation after create: %s", op.Describe())
+		}
+	}
+
+	executor := NewExecutor(database)
+	if err := executor.Migrate([]*Migration{{ID: "0001_initial"}}); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	applied, err := executor.getAppliedMigrations()
+	if err != nil {
+		t.Fatalf("getAppliedMigrations failed: %v", err)
+	}
+	if _, ok := applied["0001_initial"]; !ok {
+		t.Errorf("expected 0001_initial to be recorded, got %v", applied)
+	}
+}
+
+func TestExecutorRoute(t *testing.T) {
+	database, err := db.NewDB("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer database.Close()
+
+	m := &Migration{ID: "0001_initial", Operations: []Operation{
+		&CreateTable{Name: "users", Fields: map[string]string{"id": "SERIAL PRIMARY KEY"}},
+		&CreateTable{Name: "events", Fields: map[string]string{"id": "SERIAL PRIMARY KEY"}},
+	}}
+	allow := func(alias, table string) bool { return table != "events" }
+	if err := NewExecutor(database).Route("default", allow).Migrate([]*Migration{m}); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	tables, _ := database.GetTables()
+	sort.Strings(tables)
+	if strings.Join(tables, ",") != "go_migrations,users" {
+		t.Errorf("expected events to be skipped, got tables %v", tables)
+	}
+}
@@ -13,6 +13,8 @@ import (
 type Executor struct {
 	db           *db.DB
 	trackerTable string
+	alias        string
+	allow        func(alias, table string) bool
 }
 
 func NewExecutor(database *db.DB) *Executor {
@@ -22,12 +24,22 @@ func NewExecutor(database *db.DB) *Executor {
 	}
 }
 
+// Route makes the executor skip table operations that allow rejects for
+// alias, e.g. connections.Handler.AllowMigrate. Skipped migrations are
+// still recorded as applied on this database.
+func (e *Executor) Route(alias string, allow func(alias, table string) bool) *Executor {
+	e.alias = alias
+	e.allow = allow
+	return e
+}
+
 // Setup creates the migration tracker table if it doesn't exist
 func (e *Executor) Setup() error {
+	d := e.db.Ops()
 	return e.db.CreateTable(e.trackerTable, map[string]string{
-		"id":         "SERIAL PRIMARY KEY",
-		"name":       "VARCHAR(255) UNIQUE NOT NULL",
-		"applied_at": "TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP",
+		"id":         d.DataType("AutoField"),
+		"name":       db.ColumnType(d, "CharField", 255) + " UNIQUE NOT NULL",
+		"applied_at": d.DataType("DateTimeField") + " DEFAULT CURRENT_TIMESTAMP",
 	})
 }
 
@@ -55,6 +67,10 @@ func (e *Executor) Migrate(availableMigrations []*Migration) error {
 
 		log.Printf("Applying migration %s...", m.ID)
 		for _, op := range m.Operations {
+			if t, ok := op.(TableOperation); ok && e.allow != nil && !e.allow(e.alias, t.Table()) {
+				log.Printf("  - %s (skipped on %s)", op.Describe(), e.alias)
+				continue
+			}
 			log.Printf("  - %s", op.Describe())
 			if err := op.Apply(e.db); err != nil {
 				return fmt.Errorf("failed to apply migration %s: %w", m.ID, err)
@@ -90,6 +106,6 @@ func (e *Executor) getAppliedMigrations() (map[string]time.Time, error) {
 }
 
 func (e *Executor) markApplied(name string) error {
-	_, err := e.db.Exec(fmt.Sprintf("INSERT INTO %s (name) VALUES ($1)", e.trackerTable), name)
+	_, err := e.db.Exec(fmt.Sprintf("INSERT INTO %s (name) VALUES (%s)", e.trackerTable, e.db.Ops().Placeholder(1)), name)
 	return err
 }
@@ -13,6 +13,13 @@ type Operation interface {
 	Describe() string
 }
 
+// TableOperation is implemented by operations that change a single table,
+// letting the executor ask a router whether to apply them on a database
+type TableOperation interface {
+	Operation
+	Table() string
+}
+
 // Migration represents a set of operations to be applied to the database
 type Migration struct {
 	ID           string
@@ -34,6 +41,10 @@ func (o *CreateTable) Describe() string {
 	return "Create table " + o.Name
 }
 
+func (o *CreateTable) Table() string {
+	return o.Name
+}
+
 // AddField operation
 type AddField struct {
 	TableName string
@@ -42,7 +53,7 @@ type AddField struct {
 }
 
 func (o *AddField) Apply(database *db.DB) error {
-	query := "ALTER TABLE " + o.TableName + " ADD COLUMN " + o.FieldName + " " + o.FieldType
+	query := "ALTER TABLE " + o.TableName + " ADD COLUMN " + o.FieldName + " " + database.ColumnDefinition(o.FieldType)
 	_, err := database.Exec(query)
 	return err
 }
@@ -51,6 +62,10 @@ func (o *AddField) Describe() string {
 	return "Add field " + o.FieldName + " to " + o.TableName
 }
 
+func (o *AddField) Table() string {
+	return o.TableName
+}
+
 // AlterField operation
 type AlterField struct {
 	TableName string
@@ -59,6 +74,11 @@ type AlterField struct {
 }
 
 func (o *AlterField) Apply(database *db.DB) error {
+	if database.Ops().Name() == "sqlite3" {
+		// SQLite has no ALTER COLUMN; changing a column requires rebuilding the table
+		return fmt.Errorf("sqlite3 does not support altering column %s.%s; use RunSQL to rebuild the table", o.TableName, o.FieldName)
+	}
+
 	// Parse FieldType: e.g. "TEXT UNIQUE NOT NULL"
 	parts := strings.Split(o.FieldType, " ")
 	baseType := parts[0]
@@ -117,6 +137,10 @@ func (o *AlterField) Describe() string {
 	return "Alter field " + o.FieldName + " on " + o.TableName
 }
 
+func (o *AlterField) Table() string {
+	return o.TableName
+}
+
 // RemoveField operation
 type RemoveField struct {
 	TableName string
@@ -125,6 +149,10 @@ type RemoveField struct {
 
 func (o *RemoveField) Apply(database *db.DB) error {
 	query := fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s CASCADE", o.TableName, o.FieldName)
+	if database.Ops().Name() == "sqlite3" {
+		// SQLite (3.35+) supports DROP COLUMN but not IF EXISTS or CASCADE
+		query = fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", o.TableName, o.FieldName)
+	}
 	_, err := database.Exec(query)
 	return err
 }
@@ -133,6 +161,10 @@ func (o *RemoveField) Describe() string {
 	return "Remove field " + o.FieldName + " from " + o.TableName
 }
 
+func (o *RemoveField) Table() string {
+	return o.TableName
+}
+
 // RunSQL operation
 type RunSQL struct {
 	SQL string
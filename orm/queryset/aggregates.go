@@ -0,0 +1,161 @@
+package queryset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Aggregate computes a value over a set of rows, e.g. Sum("views") or
+// Count("books"). Fields may follow relations with "__"; a path ending at a
+// relation refers to the related rows' primary keys.
+type Aggregate struct {
+	Function string
+	// Field is a field path, or "*" for Count
+	Field    string
+	Distinct bool
+}
+
+// Count counts rows, or the non-NULL values of field ("*" counts every row)
+func Count(field string) *Aggregate { return &Aggregate{Function: "COUNT", Field: field} }
+
+// Sum adds the values of field
+func Sum(field string) *Aggregate { return &Aggregate{Function: "SUM", Field: field} }
+
+// Avg averages the values of field
+func Avg(field string) *Aggregate { return &Aggregate{Function: "AVG", Field: field} }
+
+// Min returns the smallest value of field
+func Min(field string) *Aggregate { return &Aggregate{Function: "MIN", Field: field} }
+
+// Max returns the largest value of field
+func Max(field string) *Aggregate { return &Aggregate{Function: "MAX", Field: field} }
+
+// WithDistinct only aggregates distinct values, e.g. Count("tags").WithDistinct()
+func (a *Aggregate) WithDistinct() *Aggregate {
+	cp := *a
+	cp.Distinct = true
+	return &cp
+}
+
+func (a *Aggregate) sql(c *compiler) string {
+	arg := "*"
+	if a.Field != "*" {
+		// Aggregates keep rows without related rows, e.g. authors with no books count 0
+		arg = c.column(a.Field, true)
+	}
+	if a.Distinct {
+		arg = "DISTINCT " + arg
+	}
+	return fmt.Sprintf("%s(%s)", a.Function, arg)
+}
+
+// containsAggregate reports whether evaluating v requires grouping rows
+func containsAggregate(v interface{}) bool {
+	switch v := v.(type) {
+	case *Aggregate:
+		return true
+	case *WindowExpression:
+		// Window functions are computed after grouping, over the rows
+		return false
+	default:
+		return anyOperand(v, containsAggregate)
+	}
+}
+
+// annotation is a named expression added to the select list
+type annotation struct {
+	name string
+	expr Expression
+}
+
+// sortedAnnotations returns the annotations in name order, so their SQL is stable
+func sortedAnnotations(exprs map[string]Expression) []annotation {
+	annotations := make([]annotation, 0, len(exprs))
+	for name, expr := range exprs {
+		annotations = append(annotations, annotation{name: name, expr: expr})
+	}
+	sort.Slice(annotations, func(i, j int) bool { return annotations[i].name < annotations[j].name })
+	return annotations
+}
+
+// normalize converts driver values into plain Go values. Drivers return some
+// numeric results (e.g. Postgres SUM over integers) as text.
+func normalize(v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	s := string(b)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// Annotate adds a computed column to each result, e.g.
+//
+//	qs.Annotate(map[string]Expression{"book_count": Count("books")})
+//
+// Aggregating annotations group the results by row. Values are loaded into
+// fields tagged `drf:"book_count;annotation"`, or passed to models
+// implementing ExtraColumns, and can be used in OrderBy.
+func (q *QuerySet[T]) Annotate(annotations map[string]Expression) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.annotations = append(newQs.annotations, sortedAnnotations(annotations)...)
+	return newQs
+}
+
+// annotation returns the named annotation, or nil
+func (q *QuerySet[T]) annotation(name string) *annotation {
+	for i := range q.annotations {
+		if q.annotations[i].name == name {
+			return &q.annotations[i]
+		}
+	}
+	return nil
+}
+
+// Aggregate computes aggregates over all matching rows and returns them by name:
+//
+//	stats, err := qs.Aggregate(map[string]Expression{"total": Sum("views"), "avg": Avg("views")})
+//
+// Aggregates over no rows are nil, except Count which is 0.
+func (q *QuerySet[T]) Aggregate(aggregates map[string]Expression) (map[string]interface{}, error) {
+	if q.limit > 0 || q.offset > 0 {
+		return nil, fmt.Errorf("cannot aggregate a queryset once a limit or offset has been applied")
+	}
+	for _, a := range q.annotations {
+		if containsAggregate(a.expr) {
+			return nil, fmt.Errorf("cannot aggregate over the aggregate annotation %q", a.name)
+		}
+	}
+
+	q, err := q.resolve(false)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := sortedAnnotations(aggregates)
+	query, args, err := q.compile(&projection{aggregate: selected})
+	if err != nil {
+		return nil, err
+	}
+
+	dest := make([]interface{}, len(selected))
+	for i := range dest {
+		dest[i] = new(interface{})
+	}
+	if err := q.db.QueryRowContext(q.getContext(), query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(selected))
+	for i, a := range selected {
+		result[a.name] = normalize(*dest[i].(*interface{}))
+	}
+	return result, nil
+}
@@ -0,0 +1,164 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+type liteAuthor struct {
+	ID        uint64     `drf:"id;primary_key;auto_increment"`
+	Name      string     `drf:"name;max_length=100"`
+	Books     []liteBook `drf:"relation=lite_books.author_id"`
+	BookCount int64      `drf:"book_count;annotation"`
+}
+
+func (a *liteAuthor) TableName() string { return "lite_authors" }
+
+type liteBook struct {
+	ID       uint64 `drf:"id;primary_key;auto_increment"`
+	Title    string `drf:"title;max_length=100"`
+	Pages    int64  `drf:"pages"`
+	AuthorID uint64 `drf:"author_id;foreign_key=lite_authors.id"`
+}
+
+func (b *liteBook) TableName() string { return "lite_books" }
+
+// newLibraryDB returns a database with three authors: Ann wrote two books,
+// Bob one and Cid none. Only Ann has a profile.
+func newLibraryDB(t *testing.T) *db.DB {
+	t.Helper()
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_profiles", map[string]string{
+		"id":  "SERIAL PRIMARY KEY",
+		"bio": "TEXT NOT NULL",
+	})
+	database.CreateTable("lite_authors", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"name":       "VARCHAR(100) NOT NULL",
+		"profile_id": "BIGINT",
+	})
+	database.CreateTable("lite_books", map[string]string{
+		"id":        "SERIAL PRIMARY KEY",
+		"title":     "VARCHAR(100) NOT NULL",
+		"pages":     "BIGINT NOT NULL",
+		"author_id": "BIGINT NOT NULL",
+	})
+
+	authors := NewQuerySet[*liteAuthor](database)
+	books := NewQuerySet[*liteBook](database)
+	for _, name := range []string{"Ann", "Bob", "Cid"} {
+		if err := authors.Create(&liteAuthor{Name: name}); err != nil {
+			t.Fatalf("Create author failed: %v", err)
+		}
+	}
+	for _, b := range []*liteBook{
+		{Title: "Go", Pages: 100, AuthorID: 1},
+		{Title: "SQL", Pages: 300, AuthorID: 1},
+		{Title: "Rust", Pages: 200, AuthorID: 2},
+	} {
+		if err := books.Create(b); err != nil {
+			t.Fatalf("Create book failed: %v", err)
+		}
+	}
+	if _, err := database.Exec("INSERT INTO lite_profiles (bio) VALUES ('Writes about Go')"); err != nil {
+		t.Fatalf("insert profile failed: %v", err)
+	}
+	if _, err := database.Exec("UPDATE lite_authors SET profile_id = 1 WHERE name = 'Ann'"); err != nil {
+		t.Fatalf("update author failed: %v", err)
+	}
+	return database
+}
+
+func TestAggregateSQL(t *testing.T) {
+	qs := &QuerySet[*liteAuthor]{}
+
+	sql, _, _ := qs.Annotate(map[string]Expression{"book_count": Count("books")}).OrderBy("-book_count").SQL()
+	expected := `SELECT lite_authors.*, COUNT(lite_books.id) AS "book_count" FROM lite_authors ` +
+		`LEFT JOIN lite_books ON lite_authors.id = lite_books.author_id GROUP BY lite_authors.id ORDER BY "book_count" DESC`
+	if sql != expected {
+		t.Errorf("annotate:\n got %q\nwant %q", sql, expected)
+	}
+
+	sql, _, _ = (&QuerySet[*liteBook]{}).Values("author_id").Annotate(map[string]Expression{"pages": Sum("pages")}).SQL()
+	expected = `SELECT lite_books.author_id AS "author_id", SUM(lite_books.pages) AS "pages" FROM lite_books GROUP BY lite_books.author_id`
+	if sql != expected {
+		t.Errorf("values annotate:\n got %q\nwant %q", sql, expected)
+	}
+
+	sql, _, _ = (&QuerySet[*liteBook]{}).Values("author__name").SQL()
+	expected = `SELECT lite_authors.name AS "author__name" FROM lite_books LEFT JOIN lite_authors ON lite_books.author_id = lite_authors.id`
+	if sql != expected {
+		t.Errorf("values across relation:\n got %q\nwant %q", sql, expected)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database)
+
+	stats, err := books.Aggregate(map[string]Expression{
+		"total": Sum("pages"),
+		"avg":   Avg("pages"),
+		"min":   Min("pages"),
+		"max":   Max("pages"),
+		"count": Count("*"),
+	})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if fmt.Sprint(stats["total"], stats["avg"], stats["min"], stats["max"], stats["count"]) != "600 200 100 300 3" {
+		t.Errorf("unexpected aggregates: %v", stats)
+	}
+
+	filtered, err := books.Filter(Q{"pages__gt": 150}).Aggregate(map[string]Expression{"total": Sum("pages")})
+	if err != nil || fmt.Sprint(filtered["total"]) != "500" {
+		t.Errorf("filtered aggregate: %v %v", filtered, err)
+	}
+
+	distinct, _ := books.Aggregate(map[string]Expression{"authors": Count("author_id").WithDistinct()})
+	if fmt.Sprint(distinct["authors"]) != "2" {
+		t.Errorf("distinct count: %v", distinct)
+	}
+
+	if _, err := books.Limit(1).Aggregate(map[string]Expression{"total": Sum("pages")}); err == nil {
+		t.Error("expected an error aggregating a sliced queryset")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	database := newLibraryDB(t)
+	authors := NewQuerySet[*liteAuthor](database)
+
+	results, err := authors.Annotate(map[string]Expression{"book_count": Count("books")}).OrderBy("-book_count", "name").All()
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	var got []string
+	for _, a := range results {
+		got = append(got, fmt.Sprintf("%s=%d", a.Name, a.BookCount))
+	}
+	if strings.Join(got, ",") != "Ann=2,Bob=1,Cid=0" {
+		t.Errorf("unexpected annotations: %v", got)
+	}
+
+	// Counting a grouped queryset counts groups, not joined rows
+	if n, err := authors.Annotate(map[string]Expression{"book_count": Count("books")}).Count(); err != nil || n != 3 {
+		t.Errorf("expected 3 annotated authors, got %d (%v)", n, err)
+	}
+	if n, err := authors.Filter(Q{"name__icontains": "a"}).Count(); err != nil || n != 1 {
+		t.Errorf("expected 1 filtered author, got %d (%v)", n, err)
+	}
+
+	rows, err := NewQuerySet[*liteBook](database).Values("author_id").
+		Annotate(map[string]Expression{"pages": Sum("pages"), "books": Count("*")}).
+		OrderBy("author_id").All()
+	if err != nil {
+		t.Fatalf("Values().Annotate() failed: %v", err)
+	}
+	if fmt.Sprint(rows) != "[map[author_id:1 books:2 pages:400] map[author_id:2 books:1 pages:200]]" {
+		t.Errorf("unexpected grouped rows: %v", rows)
+	}
+}
@@ -0,0 +1,311 @@
+package queryset
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// maxBulkParams caps the arguments of one bulk statement below the limits of
+// SQLite (32766) and PostgreSQL (65535)
+const maxBulkParams = 32766
+
+// batches splits n rows of width columns into batches of at most size rows
+// (all rows if size <= 0) that stay under maxBulkParams
+func batches(n, width, size int) [][2]int {
+	if width > 0 && (size <= 0 || size*width > maxBulkParams) {
+		size = maxBulkParams / width
+	}
+	if size <= 0 || size > n {
+		size = n
+	}
+
+	var ranges [][2]int
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
+
+// structValue returns the struct obj holds, dereferencing pointers
+func structValue(obj interface{}) reflect.Value {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v
+}
+
+// BulkCreate inserts objs with one multi-row INSERT per batch of batchSize
+// objects (0 for as many as the database accepts) and sets their IDs. All
+// batches run in one transaction. Model hooks and signals are not called.
+//
+// IDs come from RETURNING where supported; otherwise they are derived from
+// the last inserted id, which SQLite assigns consecutively within a statement.
+func (q *QuerySet[T]) BulkCreate(objs []T, batchSize int) error {
+	if len(objs) == 0 {
+		return nil
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var columns []string
+	rows := make([][]interface{}, len(objs))
+	for i, obj := range objs {
+		val := structValue(obj)
+		setCreateTimestamps(val, now)
+		columns, rows[i] = collectFields(val)
+	}
+
+	table := q.getTableName()
+	return q.db.Atomic(q.getContext(), func(tx *db.DB) error {
+		for _, batch := range batches(len(objs), len(columns), batchSize) {
+			if err := q.insertBatch(tx, table, columns, objs[batch[0]:batch[1]], rows[batch[0]:batch[1]]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (q *QuerySet[T]) insertBatch(tx *db.DB, table string, columns []string, objs []T, rows [][]interface{}) error {
+	d := tx.Ops()
+	c := newCompiler(d, nil)
+	tuples := make([]string, len(rows))
+	for i, row := range rows {
+		placeholders := make([]string, len(row))
+		for j, v := range row {
+			placeholders[j] = c.param(v)
+		}
+		tuples[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(tuples, ", "))
+
+	ids := make([]interface{}, 0, len(objs))
+	if d.SupportsReturning() {
+		rs, err := tx.QueryContext(q.getContext(), query+" RETURNING id", c.args...)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		for rs.Next() {
+			var id interface{}
+			if err := rs.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err := rs.Err(); err != nil {
+			return err
+		}
+	} else {
+		res, err := tx.ExecContext(q.getContext(), query, c.args...)
+		if err != nil {
+			return err
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for i := range objs {
+			ids = append(ids, lastID-int64(len(objs)-1-i))
+		}
+	}
+
+	for i, obj := range objs {
+		if i >= len(ids) {
+			break
+		}
+		if idField := structValue(obj).FieldByName("ID"); idField.IsValid() && idField.CanSet() {
+			setField(idField, ids[i])
+		}
+	}
+	return nil
+}
+
+// CopyFrom inserts objs with PostgreSQL's COPY protocol, the fastest way to
+// load large amounts of data. Unlike BulkCreate it does not set IDs. It
+// returns db.ErrCopyUnsupported on other databases.
+func (q *QuerySet[T]) CopyFrom(objs []T) (int64, error) {
+	if len(objs) == 0 {
+		return 0, nil
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var columns []string
+	rows := make([][]interface{}, len(objs))
+	for i, obj := range objs {
+		val := structValue(obj)
+		setCreateTimestamps(val, now)
+		columns, rows[i] = collectFields(val)
+	}
+	return q.db.CopyFrom(q.getContext(), q.getTableName(), columns, rows)
+}
+
+// BulkUpdate saves the given fields of objs, identified by their IDs, with
+// one UPDATE ... FROM (VALUES ...) per batch of batchSize objects (0 for as
+// many as the database accepts). All batches run in one transaction. It
+// returns the number of rows updated. auto_now fields are not touched.
+func (q *QuerySet[T]) BulkUpdate(objs []T, fields []string, batchSize int) (int64, error) {
+	if len(objs) == 0 {
+		return 0, nil
+	}
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("BulkUpdate requires at least one field")
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return 0, err
+	}
+
+	meta := q.meta()
+	pk := meta.field("id")
+	if pk == nil {
+		return 0, fmt.Errorf("%s has no id field", meta.Type.Name())
+	}
+	columns := []*fieldMeta{pk}
+	for _, name := range fields {
+		f := meta.field(name)
+		if f == nil || hasOption(f.Tag, "relation") || hasOption(f.Tag, "m2m") || hasOption(f.Tag, "annotation") {
+			return 0, fmt.Errorf("%s has no updatable field %q", meta.Type.Name(), name)
+		}
+		if f == pk {
+			return 0, fmt.Errorf("BulkUpdate cannot update the primary key")
+		}
+		columns = append(columns, f)
+	}
+
+	var updated int64
+	err = q.db.Atomic(q.getContext(), func(tx *db.DB) error {
+		for _, batch := range batches(len(objs), len(columns), batchSize) {
+			query, args := bulkUpdateSQL(tx.Ops(), meta.Table, columns, objs[batch[0]:batch[1]])
+			res, err := tx.ExecContext(q.getContext(), query, args...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			updated += n
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// bulkUpdateSQL renders an UPDATE joining the table to a VALUES list holding
+// the id and new values of each object. Dialects without column alias lists
+// (SQLite) name the VALUES columns column1, column2, ...; the others (such
+// as PostgreSQL) need the parameters cast, as they would otherwise type
+// every parameter as text.
+func bulkUpdateSQL[T any](d db.Dialect, table string, columns []*fieldMeta, objs []T) (string, []interface{}) {
+	named := d.ValuesColumnAliases()
+	ref := func(i int) string {
+		if named {
+			return "v." + columns[i].Column
+		}
+		return fmt.Sprintf("v.column%d", i+1)
+	}
+
+	c := newCompiler(d, nil)
+	tuples := make([]string, len(objs))
+	for i, obj := range objs {
+		val := structValue(obj)
+		values := make([]string, len(columns))
+		for j, col := range columns {
+			values[j] = d.CastParam(c.param(columnValue(val.FieldByIndex(col.Index), col.Tag)), castType(d, col))
+		}
+		tuples[i] = "(" + strings.Join(values, ", ") + ")"
+	}
+
+	sets := make([]string, len(columns)-1)
+	for i := range sets {
+		sets[i] = fmt.Sprintf("%s = %s", columns[i+1].Column, ref(i+1))
+	}
+
+	alias := "v"
+	if named {
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.Column
+		}
+		alias = "v(" + strings.Join(names, ", ") + ")"
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s FROM (VALUES %s) AS %s WHERE %s.id = %s",
+		table, strings.Join(sets, ", "), strings.Join(tuples, ", "), alias, table, ref(0))
+	return query, c.args
+}
+
+// castType returns the column type a model field is stored as in d,
+// following the migrations' mapping, or "" if unknown
+func castType(d db.Dialect, f *fieldMeta) string {
+	if explicit := getOptionValue(f.Tag, "type"); explicit != "" {
+		return strings.ToUpper(explicit)
+	}
+	if hasOption(f.Tag, "foreign_key") || hasOption(f.Tag, "one_to_one") {
+		// Foreign keys, including those holding the related model, store its id
+		return d.DataType("BigIntegerField")
+	}
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.String() == "time.Time" {
+		return d.DataType("DateTimeField")
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return d.DataType("BooleanField")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return d.DataType("BigIntegerField")
+	case reflect.Float32, reflect.Float64:
+		return d.DataType("FloatField")
+	case reflect.String:
+		return d.DataType("TextField")
+	case reflect.Map:
+		return d.DataType("JSONField")
+	case reflect.Struct:
+		// Structs scanning themselves, such as sql.NullString, are scalars
+		if reflect.PointerTo(t).Implements(scannerType) {
+			return ""
+		}
+		return d.DataType("JSONField")
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return d.DataType("BinaryField")
+		}
+		elem := "TextField"
+		switch t.Elem().Kind() {
+		case reflect.Int32, reflect.Int:
+			elem = "IntegerField"
+		case reflect.Int64:
+			elem = "BigIntegerField"
+		case reflect.Float64:
+			elem = "FloatField"
+		case reflect.Bool:
+			elem = "BooleanField"
+		}
+		return db.ColumnType(d, "ArrayField", d.DataType(elem))
+	}
+	return ""
+}
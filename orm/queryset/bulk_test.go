@@ -0,0 +1,168 @@
+package queryset
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+	"github.com/anuragcarret/djang-drf-go/orm/db/dbtest"
+	"github.com/lib/pq"
+)
+
+// liteSettings is stored as JSON
+type liteSettings map[string]interface{}
+
+func (s liteSettings) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *liteSettings) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	}
+	return fmt.Errorf("cannot scan %T into settings", src)
+}
+
+type litePreference struct {
+	ID       uint64       `drf:"id;primary_key;auto_increment"`
+	Settings liteSettings `drf:"settings"`
+}
+
+func (p *litePreference) TableName() string { return "lite_preferences" }
+
+type liteScoreboard struct {
+	ID     uint64         `drf:"id;primary_key;auto_increment"`
+	Scores pq.Int64Array  `drf:"scores"`
+	Names  pq.StringArray `drf:"names"`
+}
+
+func (s *liteScoreboard) TableName() string { return "lite_scoreboards" }
+
+func TestBulkUpdateSQL(t *testing.T) {
+	meta := metaOf(reflect.TypeOf(liteBook{}))
+	columns := []*fieldMeta{meta.field("id"), meta.field("title"), meta.field("pages")}
+	objs := []*liteBook{{ID: 1, Title: "Go", Pages: 10}, {ID: 2, Title: "SQL", Pages: 20}}
+
+	sql, args := bulkUpdateSQL(db.PostgresDialect{}, "lite_books", columns, objs)
+	expected := "UPDATE lite_books SET title = v.title, pages = v.pages FROM (VALUES " +
+		"(CAST($1 AS BIGINT), CAST($2 AS TEXT), CAST($3 AS BIGINT)), (CAST($4 AS BIGINT), CAST($5 AS TEXT), CAST($6 AS BIGINT))" +
+		") AS v(id, title, pages) WHERE lite_books.id = v.id"
+	if sql != expected || len(args) != 6 {
+		t.Errorf("postgres:\n got %q\nwant %q (%v)", sql, expected, args)
+	}
+
+	sql, _ = bulkUpdateSQL(db.SQLiteDialect{}, "lite_books", columns, objs)
+	expected = "UPDATE lite_books SET title = v.column2, pages = v.column3 FROM (VALUES (?1, ?2, ?3), (?4, ?5, ?6)) AS v WHERE lite_books.id = v.column1"
+	if sql != expected {
+		t.Errorf("sqlite:\n got %q\nwant %q", sql, expected)
+	}
+
+	prefs := metaOf(reflect.TypeOf(litePreference{}))
+	sql, _ = bulkUpdateSQL(db.PostgresDialect{}, "lite_preferences", []*fieldMeta{prefs.field("id"), prefs.field("settings")},
+		[]*litePreference{{ID: 1, Settings: liteSettings{"theme": "dark"}}})
+	expected = "UPDATE lite_preferences SET settings = v.settings FROM (VALUES (CAST($1 AS BIGINT), CAST($2 AS JSONB))) " +
+		"AS v(id, settings) WHERE lite_preferences.id = v.id"
+	if sql != expected {
+		t.Errorf("postgres json:\n got %q\nwant %q", sql, expected)
+	}
+
+	boards := metaOf(reflect.TypeOf(liteScoreboard{}))
+	sql, _ = bulkUpdateSQL(db.PostgresDialect{}, "lite_scoreboards", []*fieldMeta{boards.field("id"), boards.field("scores"), boards.field("names")},
+		[]*liteScoreboard{{ID: 1, Scores: pq.Int64Array{3, 1}, Names: pq.StringArray{"a"}}})
+	expected = "UPDATE lite_scoreboards SET scores = v.scores, names = v.names FROM (VALUES (CAST($1 AS BIGINT), " +
+		"CAST($2 AS BIGINT[]), CAST($3 AS TEXT[]))) AS v(id, scores, names) WHERE lite_scoreboards.id = v.id"
+	if sql != expected {
+		t.Errorf("postgres arrays:\n got %q\nwant %q", sql, expected)
+	}
+}
+
+func TestBulkUpdateJSON(t *testing.T) {
+	database := newSQLiteDB(t)
+	if _, err := database.Exec("CREATE TABLE lite_preferences (id INTEGER PRIMARY KEY AUTOINCREMENT, settings TEXT NOT NULL)"); err != nil {
+		t.Fatalf("create table failed: %v", err)
+	}
+	prefs := NewQuerySet[*litePreference](database)
+	objs := []*litePreference{{Settings: liteSettings{"theme": "light"}}, {Settings: liteSettings{}}}
+	if err := prefs.BulkCreate(objs, 0); err != nil {
+		t.Fatalf("BulkCreate failed: %v", err)
+	}
+
+	objs[0].Settings["theme"], objs[1].Settings["lang"] = "dark", "go"
+	if n, err := prefs.BulkUpdate(objs, []string{"settings"}, 0); err != nil || n != 2 {
+		t.Fatalf("BulkUpdate failed: %d, %v", n, err)
+	}
+	results, err := prefs.OrderBy("id").All()
+	if err != nil || len(results) != 2 || results[0].Settings["theme"] != "dark" || results[1].Settings["lang"] != "go" {
+		t.Errorf("expected the JSON settings to be updated, got %+v, %v", results, err)
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database)
+
+	var objs []*liteBook
+	for i := 1; i <= 5; i++ {
+		objs = append(objs, &liteBook{Title: fmt.Sprintf("Vol %d", i), Pages: int64(i), AuthorID: 3})
+	}
+
+	dbtest.AssertNumQueries(t, 3, func() {
+		if err := books.BulkCreate(objs, 2); err != nil {
+			t.Fatalf("BulkCreate failed: %v", err)
+		}
+	})
+	for i, b := range objs {
+		if b.ID != uint64(4+i) {
+			t.Errorf("expected %s to get id %d, got %d", b.Title, 4+i, b.ID)
+		}
+	}
+	if n, _ := books.Filter(Q{"author_id": 3}).Count(); n != 5 {
+		t.Errorf("expected 5 books for Cid, got %d", n)
+	}
+
+	for _, b := range objs {
+		b.Pages *= 100
+		b.Title = "changed"
+	}
+	var updated int64
+	dbtest.AssertNumQueries(t, 2, func() {
+		var err error
+		if updated, err = books.BulkUpdate(objs, []string{"pages"}, 3); err != nil {
+			t.Fatalf("BulkUpdate failed: %v", err)
+		}
+	})
+	if updated != 5 {
+		t.Errorf("expected 5 updated rows, got %d", updated)
+	}
+	stats, _ := books.Filter(Q{"author_id": 3}).Aggregate(map[string]Expression{"pages": Sum("pages")})
+	if stats["pages"] != int64(1500) {
+		t.Errorf("expected 1500 pages, got %v", stats["pages"])
+	}
+	if n, _ := books.Filter(Q{"title": "changed"}).Count(); n != 0 {
+		t.Errorf("fields not listed must not be updated, %d were", n)
+	}
+
+	if _, err := books.BulkUpdate(objs, []string{"id"}, 0); err == nil {
+		t.Error("expected an error updating the primary key")
+	}
+	if _, err := books.CopyFrom(objs); !errors.Is(err, db.ErrCopyUnsupported) {
+		t.Errorf("expected ErrCopyUnsupported on SQLite, got %v", err)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	if got := fmt.Sprint(batches(5, 3, 2)); got != "[[0 2] [2 4] [4 5]]" {
+		t.Errorf("unexpected batches %s", got)
+	}
+	if got := len(batches(40000, 2, 0)); got != 3 {
+		t.Errorf("expected parameter limits to split 40000 rows into 3 batches, got %d", got)
+	}
+}
@@ -0,0 +1,444 @@
+package queryset
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/anuragcarret/djang-drf-go/core/apps"
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+	"github.com/anuragcarret/djang-drf-go/orm/signals"
+)
+
+// on_delete behaviours of a foreign_key or one_to_one field, e.g.
+// `drf:"author_id;foreign_key=authors.id;on_delete=cascade"`. Without the
+// option, rows referencing a deleted row are left to the database.
+const (
+	Cascade    = "cascade"
+	Protect    = "protect"
+	Restrict   = "restrict"
+	SetNull    = "set_null"
+	SetDefault = "set_default"
+	DoNothing  = "do_nothing"
+)
+
+// ProtectedError is returned by Delete when rows to be deleted are
+// referenced through an on_delete=protect foreign key
+type ProtectedError struct {
+	// Table and Column identify the protecting foreign key
+	Table  string
+	Column string
+	// Objects are the referencing rows
+	Objects []interface{}
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("cannot delete rows referenced through the protected foreign key %s.%s (%d referencing rows)",
+		e.Table, e.Column, len(e.Objects))
+}
+
+// RestrictedError is returned by Delete when rows to be deleted are
+// referenced through an on_delete=restrict foreign key, and the referencing
+// rows are not themselves deleted by a cascade
+type RestrictedError struct {
+	Table   string
+	Column  string
+	Objects []interface{}
+}
+
+func (e *RestrictedError) Error() string {
+	return fmt.Sprintf("cannot delete rows referenced through the restricted foreign key %s.%s (%d referencing rows)",
+		e.Table, e.Column, len(e.Objects))
+}
+
+// Hooks run for each deleted object, matching models.PreDeleter and
+// models.PostDeleter
+type preDeleter interface {
+	PreDelete(ctx context.Context) error
+}
+
+type postDeleter interface {
+	PostDelete(ctx context.Context) error
+}
+
+// Delete deletes the rows matched by the queryset along with the rows their
+// on_delete rules reach, firing pre/post delete hooks and signals for each
+// deleted object. It returns the number of rows deleted and the count per
+// table.
+//
+//	n, perTable, err := NewQuerySet[*Author](db).Filter(Q{"name": "Ann"}).Delete()
+func (q *QuerySet[T]) Delete() (int64, map[string]int64, error) {
+	if q.limit > 0 || q.offset > 0 {
+		return 0, nil, fmt.Errorf("cannot delete a queryset once a limit or offset has been applied")
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var total int64
+	var counts map[string]int64
+	ctx := q.getContext()
+	err = q.db.Atomic(ctx, func(tx *db.DB) error {
+		matched := q.WithTx(tx)
+		matched.selectRelated = nil
+		matched.prefetchRelated = nil
+		matched.annotations = nil
+		matched.only, matched.deferred = nil, nil
+		matched.ordering = nil
+
+		results, err := matched.All()
+		if err != nil {
+			return err
+		}
+		objs := make([]reflect.Value, len(results))
+		for i, r := range results {
+			objs[i] = pointerTo(r)
+		}
+
+		c := newCollector(ctx, tx)
+		if err := c.collect(q.meta(), objs); err != nil {
+			return err
+		}
+		if err := c.checkRestricted(); err != nil {
+			return err
+		}
+		total, counts, err = c.delete()
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, counts, nil
+}
+
+// pointerTo returns a pointer to the model obj, which hooks are declared on
+func pointerTo(obj interface{}) reflect.Value {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		return v
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+// dependent is a foreign key that references a model's rows
+type dependent struct {
+	meta     *modelMeta
+	field    *fieldMeta
+	onDelete string
+}
+
+// dependentsOf returns the foreign keys referencing meta's table, found on
+// registered models and through meta's reverse relation fields
+func dependentsOf(meta *modelMeta) []dependent {
+	var deps []dependent
+	seen := map[string]bool{}
+	add := func(m *modelMeta, f *fieldMeta) {
+		key := m.Table + "." + f.Column
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		deps = append(deps, dependent{
+			meta:     m,
+			field:    f,
+			onDelete: strings.ToLower(getOptionValue(f.Tag, "on_delete")),
+		})
+	}
+
+	for _, model := range apps.Apps.GetAllModels() {
+		m := metaOf(reflect.TypeOf(model))
+		for _, f := range m.Fields {
+			if rel := f.relation(); rel != nil && rel.LocalColumn != "id" && rel.Through == "" && rel.Table == meta.Table {
+				add(m, f)
+			}
+		}
+	}
+
+	for _, f := range meta.Fields {
+		rel := f.relation()
+		if rel == nil || rel.LocalColumn != "id" || rel.Through != "" || rel.Model == nil {
+			continue
+		}
+		m := metaOf(rel.Model)
+		if fk := m.field(rel.RemoteColumn); fk != nil {
+			add(m, fk)
+		}
+	}
+	return deps
+}
+
+// throughTablesOf returns the m2m join table columns referencing meta's
+// rows, as "table.column"
+func throughTablesOf(meta *modelMeta) []string {
+	var refs []string
+	seen := map[string]bool{}
+	add := func(through, column string) {
+		ref := through + "." + column
+		if through != "" && column != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	for _, f := range meta.Fields {
+		if rel := f.relation(); rel != nil && rel.Through != "" {
+			add(rel.Through, rel.ThroughFrom)
+		}
+	}
+	for _, model := range apps.Apps.GetAllModels() {
+		for _, f := range metaOf(reflect.TypeOf(model)).Fields {
+			if rel := f.relation(); rel != nil && rel.Through != "" && rel.Table == meta.Table {
+				add(rel.Through, rel.ThroughTo)
+			}
+		}
+	}
+	return refs
+}
+
+// collected holds the objects of one table that will be deleted
+type collected struct {
+	meta *modelMeta
+	objs []reflect.Value
+	ids  []interface{}
+	seen map[string]bool
+}
+
+// fieldUpdate sets a foreign key of the rows with the given ids
+type fieldUpdate struct {
+	table, column string
+	value         interface{}
+	ids           []interface{}
+}
+
+// restriction records rows referencing deleted rows through an
+// on_delete=restrict key; deletion may only go ahead if they are collected
+type restriction struct {
+	dep  dependent
+	objs []reflect.Value
+}
+
+// collector gathers the objects a delete reaches, following on_delete rules
+// the way Django's deletion collector does
+type collector struct {
+	ctx          context.Context
+	db           *db.DB
+	tables       []string // in the order first collected
+	data         map[string]*collected
+	updates      []fieldUpdate
+	restrictions []restriction
+}
+
+func newCollector(ctx context.Context, database *db.DB) *collector {
+	return &collector{ctx: ctx, db: database, data: map[string]*collected{}}
+}
+
+// add records objs for deletion, returning those not already collected
+func (c *collector) add(meta *modelMeta, objs []reflect.Value) []reflect.Value {
+	entry := c.data[meta.Table]
+	if entry == nil {
+		entry = &collected{meta: meta, seen: map[string]bool{}}
+		c.data[meta.Table] = entry
+		c.tables = append(c.tables, meta.Table)
+	}
+
+	var added []reflect.Value
+	for _, obj := range objs {
+		id := primaryKey(obj.Elem())
+		if entry.seen[keyOf(id)] {
+			continue
+		}
+		entry.seen[keyOf(id)] = true
+		entry.objs = append(entry.objs, obj)
+		entry.ids = append(entry.ids, id)
+		added = append(added, obj)
+	}
+	return added
+}
+
+// collect adds objs and applies the on_delete rule of every foreign key
+// referencing them
+func (c *collector) collect(meta *modelMeta, objs []reflect.Value) error {
+	added := c.add(meta, objs)
+	if len(added) == 0 {
+		return nil
+	}
+	ids := make([]interface{}, len(added))
+	for i, obj := range added {
+		ids[i] = primaryKey(obj.Elem())
+	}
+
+	for _, dep := range dependentsOf(meta) {
+		if dep.onDelete == "" || dep.onDelete == DoNothing {
+			continue
+		}
+		related, err := c.fetchIn(dep.meta, dep.field.Column, ids)
+		if err != nil {
+			return err
+		}
+		if len(related) == 0 {
+			continue
+		}
+
+		switch dep.onDelete {
+		case Cascade:
+			if err := c.collect(dep.meta, related); err != nil {
+				return err
+			}
+		case Protect:
+			return &ProtectedError{Table: dep.meta.Table, Column: dep.field.Column, Objects: interfaces(related)}
+		case Restrict:
+			c.restrictions = append(c.restrictions, restriction{dep: dep, objs: related})
+		case SetNull, SetDefault:
+			var value interface{}
+			if dep.onDelete == SetDefault {
+				def := getOptionValue(dep.field.Tag, "default")
+				if def == "" {
+					return fmt.Errorf("%s.%s has on_delete=set_default but no default", dep.meta.Table, dep.field.Column)
+				}
+				value = def
+			}
+			relatedIDs := make([]interface{}, len(related))
+			for i, r := range related {
+				relatedIDs[i] = primaryKey(r.Elem())
+			}
+			c.updates = append(c.updates, fieldUpdate{table: dep.meta.Table, column: dep.field.Column, value: value, ids: relatedIDs})
+		default:
+			return fmt.Errorf("%s.%s has unknown on_delete %q", dep.meta.Table, dep.field.Column, dep.onDelete)
+		}
+	}
+	return nil
+}
+
+// checkRestricted fails if a restricting row is not itself being deleted
+func (c *collector) checkRestricted() error {
+	for _, r := range c.restrictions {
+		var blocking []reflect.Value
+		entry := c.data[r.dep.meta.Table]
+		for _, obj := range r.objs {
+			if entry == nil || !entry.seen[keyOf(primaryKey(obj.Elem()))] {
+				blocking = append(blocking, obj)
+			}
+		}
+		if len(blocking) > 0 {
+			return &RestrictedError{Table: r.dep.meta.Table, Column: r.dep.field.Column, Objects: interfaces(blocking)}
+		}
+	}
+	return nil
+}
+
+// delete runs pre-delete hooks, updates and deletes, then post-delete hooks.
+// Tables are deleted in reverse collection order, so referencing rows go
+// before the rows they reference.
+func (c *collector) delete() (int64, map[string]int64, error) {
+	for _, table := range c.tables {
+		for _, obj := range c.data[table].objs {
+			instance := obj.Interface()
+			if h, ok := instance.(preDeleter); ok {
+				if err := h.PreDelete(c.ctx); err != nil {
+					return 0, nil, err
+				}
+			}
+			signals.Send(signals.PreDelete, instance, instance, map[string]interface{}{"model": table})
+		}
+	}
+
+	for _, u := range c.updates {
+		format := fmt.Sprintf("UPDATE %s SET %s = %%s WHERE id IN (%%s)", u.table, u.column)
+		if _, err := c.execIn(u.ids, format, u.value); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var total int64
+	counts := map[string]int64{}
+	for i := len(c.tables) - 1; i >= 0; i-- {
+		table := c.tables[i]
+		entry := c.data[table]
+
+		for _, ref := range throughTablesOf(entry.meta) {
+			through, column, _ := strings.Cut(ref, ".")
+			format := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%%s)", through, column)
+			if _, err := c.execIn(entry.ids, format); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		deleted, err := c.execIn(entry.ids, fmt.Sprintf("DELETE FROM %s WHERE id IN (%%s)", table))
+		if err != nil {
+			return 0, nil, err
+		}
+		counts[table] = deleted
+		total += deleted
+	}
+
+	for _, table := range c.tables {
+		for _, obj := range c.data[table].objs {
+			instance := obj.Interface()
+			if h, ok := instance.(postDeleter); ok {
+				if err := h.PostDelete(c.ctx); err != nil {
+					return 0, nil, err
+				}
+			}
+			signals.Send(signals.PostDelete, instance, instance, map[string]interface{}{"model": table})
+		}
+	}
+	return total, counts, nil
+}
+
+// fetchIn loads the rows of meta whose column is in ids, one query per
+// batch of ids
+func (c *collector) fetchIn(meta *modelMeta, column string, ids []interface{}) ([]reflect.Value, error) {
+	var related []reflect.Value
+	for _, batch := range batches(len(ids), 1, maxBulkParams) {
+		rows, err := fetchModel(c.ctx, c.db, meta, Q{column + "__in": ids[batch[0]:batch[1]]})
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, rows...)
+	}
+	return related, nil
+}
+
+// execIn executes format once per batch of ids, keeping each statement under
+// maxBulkParams. format's verbs are filled with placeholders for leading,
+// then the batch's id list. It returns the total rows affected.
+func (c *collector) execIn(ids []interface{}, format string, leading ...interface{}) (int64, error) {
+	var total int64
+	for _, batch := range batches(len(ids), 1, maxBulkParams-len(leading)) {
+		cmp := newCompiler(c.db.Ops(), nil)
+		verbs := make([]interface{}, 0, len(leading)+1)
+		for _, v := range leading {
+			verbs = append(verbs, cmp.param(v))
+		}
+		placeholders := make([]string, 0, batch[1]-batch[0])
+		for _, id := range ids[batch[0]:batch[1]] {
+			placeholders = append(placeholders, cmp.param(id))
+		}
+		verbs = append(verbs, strings.Join(placeholders, ", "))
+
+		res, err := c.db.ExecContext(c.ctx, fmt.Sprintf(format, verbs...), cmp.args...)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// interfaces returns the objects held by objs
+func interfaces(objs []reflect.Value) []interface{} {
+	out := make([]interface{}, len(objs))
+	for i, obj := range objs {
+		out[i] = obj.Interface()
+	}
+	return out
+}
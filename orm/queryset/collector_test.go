@@ -0,0 +1,197 @@
+package queryset
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+	"github.com/anuragcarret/djang-drf-go/orm/signals"
+)
+
+type liteOwner struct {
+	ID     uint64       `drf:"id;primary_key;auto_increment"`
+	Name   string       `drf:"name"`
+	Pets   []*litePet   `drf:"relation=lite_pets.owner_id"`
+	Cars   []*liteCar   `drf:"relation=lite_cars.owner_id"`
+	Deeds  []*liteDeed  `drf:"relation=lite_deeds.owner_id"`
+	Leases []*liteLease `drf:"relation=lite_leases.owner_id"`
+}
+
+func (o *liteOwner) TableName() string { return "lite_owners" }
+
+type litePet struct {
+	ID      uint64        `drf:"id;primary_key;auto_increment"`
+	Name    string        `drf:"name"`
+	OwnerID uint64        `drf:"owner_id;foreign_key=lite_owners.id;on_delete=cascade"`
+	Collars []*liteCollar `drf:"relation=lite_collars.pet_id"`
+	Leases  []*liteLease  `drf:"relation=lite_leases.pet_id"`
+}
+
+func (p *litePet) TableName() string { return "lite_pets" }
+
+// preDeleted records the pets whose PreDelete hook ran
+var preDeleted []string
+
+func (p *litePet) PreDelete(ctx context.Context) error {
+	preDeleted = append(preDeleted, p.Name)
+	return nil
+}
+
+type liteCollar struct {
+	ID    uint64 `drf:"id;primary_key;auto_increment"`
+	PetID uint64 `drf:"pet_id;foreign_key=lite_pets.id;on_delete=CASCADE"`
+}
+
+func (c *liteCollar) TableName() string { return "lite_collars" }
+
+type liteCar struct {
+	ID      uint64 `drf:"id;primary_key;auto_increment"`
+	OwnerID uint64 `drf:"owner_id;foreign_key=lite_owners.id;null;on_delete=set_null"`
+}
+
+func (c *liteCar) TableName() string { return "lite_cars" }
+
+type liteDeed struct {
+	ID      uint64 `drf:"id;primary_key;auto_increment"`
+	OwnerID uint64 `drf:"owner_id;foreign_key=lite_owners.id;on_delete=protect"`
+}
+
+func (d *liteDeed) TableName() string { return "lite_deeds" }
+
+// liteLease can't outlive its owner, but goes with its pet
+type liteLease struct {
+	ID      uint64 `drf:"id;primary_key;auto_increment"`
+	OwnerID uint64 `drf:"owner_id;foreign_key=lite_owners.id;on_delete=restrict"`
+	PetID   uint64 `drf:"pet_id;foreign_key=lite_pets.id;on_delete=cascade"`
+}
+
+func (l *liteLease) TableName() string { return "lite_leases" }
+
+// newOwnersDB creates Ann (pets Rex with a collar, and Tom; a car; a lease
+// on Rex and one on Kit) and Bob (pet Kit with a collar; a car; a deed)
+func newOwnersDB(t *testing.T) *db.DB {
+	t.Helper()
+	database := newSQLiteDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE lite_owners (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)",
+		"CREATE TABLE lite_pets (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, owner_id BIGINT NOT NULL)",
+		"CREATE TABLE lite_collars (id INTEGER PRIMARY KEY AUTOINCREMENT, pet_id BIGINT NOT NULL)",
+		"CREATE TABLE lite_cars (id INTEGER PRIMARY KEY AUTOINCREMENT, owner_id BIGINT)",
+		"CREATE TABLE lite_deeds (id INTEGER PRIMARY KEY AUTOINCREMENT, owner_id BIGINT NOT NULL)",
+		"CREATE TABLE lite_leases (id INTEGER PRIMARY KEY AUTOINCREMENT, owner_id BIGINT NOT NULL, pet_id BIGINT NOT NULL)",
+		"INSERT INTO lite_owners (name) VALUES ('Ann'), ('Bob')",
+		"INSERT INTO lite_pets (name, owner_id) VALUES ('Rex', 1), ('Tom', 1), ('Kit', 2)",
+		"INSERT INTO lite_collars (pet_id) VALUES (1), (3)",
+		"INSERT INTO lite_cars (owner_id) VALUES (1), (2)",
+		"INSERT INTO lite_deeds (owner_id) VALUES (2)",
+		"INSERT INTO lite_leases (owner_id, pet_id) VALUES (1, 1), (1, 3)",
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return database
+}
+
+func TestDelete(t *testing.T) {
+	database := newOwnersDB(t)
+	owners := NewQuerySet[*liteOwner](database)
+
+	count := func(table, where string) int {
+		var n int
+		if err := database.QueryRow("SELECT COUNT(*) FROM " + table + " WHERE " + where).Scan(&n); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		return n
+	}
+
+	t.Run("protect blocks the delete", func(t *testing.T) {
+		_, _, err := owners.Filter(Q{"name": "Bob"}).Delete()
+		var protected *ProtectedError
+		if !errors.As(err, &protected) || protected.Table != "lite_deeds" || len(protected.Objects) != 1 {
+			t.Fatalf("expected a ProtectedError for lite_deeds, got %v", err)
+		}
+		if n := count("lite_pets", "owner_id = 2"); n != 1 {
+			t.Errorf("expected Bob's pet to survive, %d left", n)
+		}
+	})
+
+	t.Run("restrict blocks rows not deleted by a cascade", func(t *testing.T) {
+		_, _, err := owners.Filter(Q{"name": "Ann"}).Delete()
+		var restricted *RestrictedError
+		if !errors.As(err, &restricted) || len(restricted.Objects) != 1 {
+			t.Fatalf("expected a RestrictedError for the lease on Kit, got %v", err)
+		}
+		if lease := restricted.Objects[0].(*liteLease); lease.PetID != 3 {
+			t.Errorf("expected the lease on Kit to restrict, got %+v", lease)
+		}
+		if n := count("lite_owners", "1 = 1"); n != 2 {
+			t.Errorf("expected nothing deleted, %d owners left", n)
+		}
+	})
+
+	t.Run("cascades, sets null and fires hooks", func(t *testing.T) {
+		if _, counts, err := NewQuerySet[*liteLease](database).Filter(Q{"pet_id": 3}).Delete(); err != nil || counts["lite_leases"] != 1 {
+			t.Fatalf("deleting the lease on Kit: %v %v", counts, err)
+		}
+
+		var signalled []string
+		signals.Register(signals.PostDelete, "lite_pets", func(sender, instance interface{}, kwargs map[string]interface{}) {
+			signalled = append(signalled, instance.(*litePet).Name)
+		})
+		preDeleted = nil
+
+		total, counts, err := owners.Filter(Q{"name": "Ann"}).Delete()
+		if err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		// The lease on Rex is restricted by Ann but cascades from Rex
+		expected := map[string]int64{"lite_owners": 1, "lite_pets": 2, "lite_collars": 1, "lite_leases": 1}
+		if total != 5 || !reflect.DeepEqual(counts, expected) {
+			t.Errorf("expected 5 rows deleted as %v, got %d as %v", expected, total, counts)
+		}
+		if !reflect.DeepEqual(preDeleted, []string{"Rex", "Tom"}) || !reflect.DeepEqual(signalled, []string{"Rex", "Tom"}) {
+			t.Errorf("expected hooks and signals for Rex and Tom, got %v and %v", preDeleted, signalled)
+		}
+		if n := count("lite_cars", "owner_id IS NULL"); n != 1 {
+			t.Errorf("expected Ann's car to lose its owner, %d cars have none", n)
+		}
+		if n := count("lite_collars", "pet_id = 3"); n != 1 {
+			t.Errorf("expected Kit's collar to survive, got %d", n)
+		}
+	})
+
+	t.Run("rejects sliced querysets", func(t *testing.T) {
+		if _, _, err := owners.Limit(1).Delete(); err == nil {
+			t.Error("expected an error deleting a sliced queryset")
+		}
+	})
+}
+
+func TestDeleteBatches(t *testing.T) {
+	database := newOwnersDB(t)
+	// More pets, each with a collar, than SQLite accepts bind parameters in
+	// one statement
+	for _, stmt := range []string{
+		"INSERT INTO lite_owners (name) VALUES ('Cid')",
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 40000)
+			INSERT INTO lite_pets (name, owner_id) SELECT 'pet ' || i, 3 FROM n`,
+		"INSERT INTO lite_collars (pet_id) SELECT id FROM lite_pets WHERE owner_id = 3",
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() { preDeleted = nil })
+
+	_, counts, err := NewQuerySet[*liteOwner](database).Filter(Q{"name": "Cid"}).Delete()
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	expected := map[string]int64{"lite_owners": 1, "lite_pets": 40000, "lite_collars": 40000}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+}
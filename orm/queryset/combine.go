@@ -0,0 +1,91 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// combination is a compound SELECT over several querysets of one model
+type combination[T ModelInterface] struct {
+	operator string
+	parts    []*QuerySet[T]
+}
+
+// Union returns the rows matched by q or any of others, without duplicates
+// unless all is set. The combined queryset can be ordered, sliced and
+// counted, but not filtered; the ordering of q and others is ignored.
+//
+//	mine := posts.Filter(Q{"author_id": uid})
+//	shared := posts.Filter(Q{"shares__user_id": uid})
+//	page, err := mine.Union(false, shared).OrderBy("-created_at").Limit(20).All()
+func (q *QuerySet[T]) Union(all bool, others ...*QuerySet[T]) *QuerySet[T] {
+	if all {
+		return q.combine("UNION ALL", others)
+	}
+	return q.combine("UNION", others)
+}
+
+// Intersection returns the rows matched by q and by every one of others
+func (q *QuerySet[T]) Intersection(others ...*QuerySet[T]) *QuerySet[T] {
+	return q.combine("INTERSECT", others)
+}
+
+// Difference returns the rows matched by q and by none of others
+func (q *QuerySet[T]) Difference(others ...*QuerySet[T]) *QuerySet[T] {
+	return q.combine("EXCEPT", others)
+}
+
+// combine returns a queryset over the combined rows, run on q's database
+func (q *QuerySet[T]) combine(operator string, others []*QuerySet[T]) *QuerySet[T] {
+	parts := append([]*QuerySet[T]{q}, others...)
+	return &QuerySet[T]{db: q.db, alias: q.alias, ctx: q.ctx, combined: &combination[T]{operator: operator, parts: parts}}
+}
+
+// compileCombined renders the compound SELECT of a combined queryset. It is
+// wrapped in a subquery, so the ordering and slice apply to the combined
+// rows on every database.
+func (q *QuerySet[T]) compileCombined(c *compiler, p *projection) (string, error) {
+	switch {
+	case p != nil && p.aggregate != nil:
+		return "", fmt.Errorf("cannot aggregate a combined queryset")
+	case len(q.filters) > 0 || len(q.excludes) > 0:
+		return "", fmt.Errorf("cannot filter a combined queryset")
+	case q.forUpdate != nil:
+		return "", fmt.Errorf("cannot lock the rows of a combined queryset")
+	case len(q.selectRelated) > 0 || len(q.annotations) > 0 || len(q.only) > 0 || len(q.deferred) > 0 || q.distinct:
+		return "", fmt.Errorf("a combined queryset can only be ordered, sliced and counted")
+	}
+
+	selects := make([]string, len(q.combined.parts))
+	for i, part := range q.combined.parts {
+		if part.limit > 0 || part.offset > 0 {
+			return "", fmt.Errorf("cannot combine a queryset once a limit or offset has been applied")
+		}
+		sub := newCompiler(c.d, c.base)
+		sub.args = c.args
+		if c.outer != nil {
+			// Within a subquery, OuterRef columns must not be shadowed
+			sub = c.nested(c.base)
+			sub.outer = c.outer
+		}
+		query, err := part.OrderBy().compileWith(sub, p)
+		c.args = sub.args
+		if err != nil {
+			return "", err
+		}
+		selects[i] = query
+	}
+
+	c.alias = c.tableAlias("combined")
+	query := fmt.Sprintf("SELECT * FROM (%s) AS %s", strings.Join(selects, " "+q.combined.operator+" "), c.alias)
+	if order := q.orderBy(c); order != "" {
+		query += " ORDER BY " + order
+	}
+	if len(c.joins) > 0 {
+		c.fail(fmt.Errorf("a combined queryset can only be ordered by its own columns"))
+	}
+	if c.err != nil {
+		return "", c.err
+	}
+	return query + c.d.LimitOffset(q.limit, q.offset), nil
+}
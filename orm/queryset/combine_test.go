@@ -0,0 +1,86 @@
+package queryset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCombineSQL(t *testing.T) {
+	books := &QuerySet[*liteBook]{}
+	sql, args, _ := books.Filter(Q{"pages__lt": 150}).
+		Union(false, books.Filter(Q{"author__name": "Bob"}).OrderBy("title")).
+		OrderBy("-pages").Limit(5).SQL()
+	expected := "SELECT * FROM (SELECT lite_books.* FROM lite_books WHERE lite_books.pages < $1 UNION " +
+		"SELECT lite_books.* FROM lite_books INNER JOIN lite_authors ON lite_books.author_id = lite_authors.id WHERE lite_authors.name = $2) " +
+		"AS combined ORDER BY combined.pages DESC LIMIT 5"
+	if sql != expected {
+		t.Errorf("\n got %q\nwant %q", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{150, "Bob"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	for name, qs := range map[string]*QuerySet[*liteBook]{
+		"filter":             books.Union(true, books).Filter(Q{"pages": 1}),
+		"sliced part":        books.Union(true, books.Limit(1)),
+		"order by relations": books.Union(true, books).OrderBy("author__name"),
+	} {
+		if _, _, err := qs.compile(nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database)
+	short, byAnn := books.Filter(Q{"pages__lte": 200}), books.Filter(Q{"author__name": "Ann"})
+
+	titles := func(qs *QuerySet[*liteBook]) []string {
+		t.Helper()
+		results, err := qs.All()
+		if err != nil {
+			t.Fatalf("All failed: %v", err)
+		}
+		var titles []string
+		for _, b := range results {
+			titles = append(titles, b.Title)
+		}
+		return titles
+	}
+
+	if got := titles(short.Union(false, byAnn).OrderBy("-pages")); !reflect.DeepEqual(got, []string{"SQL", "Rust", "Go"}) {
+		t.Errorf("union: got %v", got)
+	}
+	if got := titles(short.Intersection(byAnn).OrderBy("title")); !reflect.DeepEqual(got, []string{"Go"}) {
+		t.Errorf("intersection: got %v", got)
+	}
+	if got := titles(short.Difference(byAnn)); !reflect.DeepEqual(got, []string{"Rust"}) {
+		t.Errorf("difference: got %v", got)
+	}
+	if got := titles(short.Union(false, byAnn).OrderBy("title").Offset(1).Limit(1)); !reflect.DeepEqual(got, []string{"Rust"}) {
+		t.Errorf("sliced union: got %v", got)
+	}
+
+	all := short.Union(true, byAnn)
+	if n, err := all.Count(); err != nil || n != 4 {
+		t.Errorf("expected UNION ALL to keep Go twice, got %d, %v", n, err)
+	}
+	if n, err := all.Limit(3).Count(); err != nil || n != 3 {
+		t.Errorf("expected the count to honor the slice, got %d, %v", n, err)
+	}
+	if ids, err := short.Union(false, byAnn).OrderBy("-id").ValuesList("id").Flat(); err != nil || len(ids) != 3 {
+		t.Errorf("expected three ids, got %v, %v", ids, err)
+	}
+
+	var iterated []string
+	for b, err := range short.Union(false, byAnn).OrderBy("pages").Iterator(2) {
+		if err != nil {
+			t.Fatalf("Iterator failed: %v", err)
+		}
+		iterated = append(iterated, b.Title)
+	}
+	if !reflect.DeepEqual(iterated, []string{"Go", "Rust", "SQL"}) {
+		t.Errorf("iterator: got %v", iterated)
+	}
+}
@@ -0,0 +1,306 @@
+package queryset
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// compiler renders nodes and expressions into SQL, numbering placeholders
+// across the whole statement and collecting the joins that lookup paths
+// such as "books__pages" need
+type compiler struct {
+	d     db.Dialect
+	args  []interface{}
+	base  *modelMeta
+	alias string
+	joins []*join
+	// aliases counts uses of each table name so repeated joins get unique aliases
+	aliases map[string]int
+	// negated is the number of enclosing NOTs
+	negated int
+	// outer compiles the statement enclosing a subquery, which OuterRef refers to
+	outer *compiler
+	// annotations are the queryset's annotations, which field names in
+	// lookups and expressions may refer to
+	annotations []annotation
+	// annotationAliases refers to annotations by their column alias rather
+	// than by their expression, once they are computed by an inner query
+	annotationAliases bool
+	// aggregateRef and windowRef record references to aggregate and window
+	// annotations, which can't be evaluated in WHERE
+	aggregateRef, windowRef bool
+	err                     error
+}
+
+// join is a table joined into the statement for a relation path
+type join struct {
+	path  string
+	alias string
+	sql   string
+	meta  *modelMeta
+	multi bool
+}
+
+func newCompiler(d db.Dialect, base *modelMeta) *compiler {
+	c := &compiler{d: d, base: base, aliases: make(map[string]int)}
+	if base != nil {
+		c.alias = base.Table
+		c.aliases[base.Table] = 1
+	}
+	return c
+}
+
+// nested returns a compiler for a subquery over base inside c's statement.
+// It continues c's placeholder numbering and table aliases, so the subquery
+// doesn't shadow tables OuterRef refers to; c takes the arguments back with
+// c.args = sub.args once the subquery is rendered.
+func (c *compiler) nested(base *modelMeta) *compiler {
+	sub := &compiler{d: c.d, base: base, args: c.args, aliases: c.aliases, outer: c}
+	sub.alias = sub.tableAlias(base.Table)
+	return sub
+}
+
+// param binds v as an argument and returns its placeholder
+func (c *compiler) param(v interface{}) string {
+	c.args = append(c.args, v)
+	return c.d.Placeholder(len(c.args))
+}
+
+// value renders an Expression inline, or binds any other value as an argument
+func (c *compiler) value(v interface{}) string {
+	if e, ok := v.(Expression); ok {
+		return e.sql(c)
+	}
+	return c.param(v)
+}
+
+// fail records the first error met while compiling
+func (c *compiler) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// col qualifies a column of the base table
+func (c *compiler) col(name string) string {
+	if c.alias == "" {
+		return name
+	}
+	return c.alias + "." + name
+}
+
+// ref renders a field path, or the expression of the annotation it names.
+// An annotation can refer to the annotations added before it.
+func (c *compiler) ref(path string) string {
+	for i, a := range c.annotations {
+		if a.name != path {
+			continue
+		}
+		if c.annotationAliases {
+			return qualify(c.alias, c.d.QuoteIdent(a.name))
+		}
+		c.aggregateRef = c.aggregateRef || containsAggregate(a.expr)
+		c.windowRef = c.windowRef || containsWindow(a.expr)
+		all := c.annotations
+		c.annotations = all[:i]
+		sql := a.expr.sql(c)
+		c.annotations = all
+		return sql
+	}
+	return c.column(path, false)
+}
+
+// column resolves a "__"-separated field path to a qualified column,
+// joining related tables as needed. A path ending at a relation refers to
+// the related row's primary key.
+func (c *compiler) column(path string, left bool) string {
+	col, _, err := c.resolve(strings.Split(path, "__"), left)
+	if err != nil {
+		c.fail(err)
+	}
+	return col
+}
+
+func (c *compiler) resolve(path []string, left bool) (string, bool, error) {
+	meta, alias, prefix, multi := c.base, c.alias, "", false
+
+	for i, name := range path {
+		last := i == len(path)-1
+
+		var f *fieldMeta
+		if meta != nil {
+			f = meta.field(name)
+		}
+		if f == nil {
+			if last {
+				// Not a declared field; trust the caller that the column exists
+				return qualify(alias, name), multi, nil
+			}
+			return "", false, fmt.Errorf("cannot resolve %q in lookup %q", name, strings.Join(path, "__"))
+		}
+
+		rel := f.relation()
+		if rel == nil {
+			if !last {
+				return "", false, fmt.Errorf("field %q in lookup %q is not a relation", name, strings.Join(path, "__"))
+			}
+			return qualify(alias, f.Column), multi, nil
+		}
+
+		if last && !rel.Multi && rel.Through == "" && rel.LocalColumn != "id" {
+			// A forward foreign key already holds the related primary key
+			return qualify(alias, rel.LocalColumn), multi, nil
+		}
+
+		j := c.join(prefix+name, alias, rel, left)
+		multi = multi || rel.Multi
+		if last {
+			return qualify(j.alias, "id"), multi, nil
+		}
+		alias, meta, prefix = j.alias, j.meta, prefix+name+"__"
+	}
+	return "", false, fmt.Errorf("empty lookup path")
+}
+
+// join adds (or reuses) the join for a relation path
+func (c *compiler) join(path, parent string, rel *relation, left bool) *join {
+	for _, j := range c.joins {
+		if j.path == path {
+			return j
+		}
+	}
+
+	kind := "INNER JOIN"
+	if left || rel.Nullable {
+		kind = "LEFT JOIN"
+	}
+
+	j := &join{path: path, multi: rel.Multi}
+	if rel.Model != nil {
+		j.meta = metaOf(rel.Model)
+	}
+
+	if rel.Through != "" {
+		through := c.tableAlias(rel.Through)
+		j.alias = c.tableAlias(rel.Table)
+		j.sql = fmt.Sprintf(" %s %s ON %s = %s %s %s ON %s = %s",
+			kind, aliased(rel.Through, through), qualify(through, rel.ThroughFrom), qualify(parent, rel.LocalColumn),
+			kind, aliased(rel.Table, j.alias), qualify(j.alias, rel.RemoteColumn), qualify(through, rel.ThroughTo))
+	} else {
+		j.alias = c.tableAlias(rel.Table)
+		j.sql = fmt.Sprintf(" %s %s ON %s = %s",
+			kind, aliased(rel.Table, j.alias), qualify(parent, rel.LocalColumn), qualify(j.alias, rel.RemoteColumn))
+	}
+
+	c.joins = append(c.joins, j)
+	return j
+}
+
+// tableAlias returns the table name the first time it is used, and a
+// numbered alias for every further use
+func (c *compiler) tableAlias(table string) string {
+	c.aliases[table]++
+	if n := c.aliases[table]; n > 1 {
+		return fmt.Sprintf("%s%d", table, n)
+	}
+	return table
+}
+
+// joinSQL renders all joins in the order they were added
+func (c *compiler) joinSQL() string {
+	var b strings.Builder
+	for _, j := range c.joins {
+		b.WriteString(j.sql)
+	}
+	return b.String()
+}
+
+// repeatsRows reports whether any join can repeat rows of the base table
+func (c *compiler) repeatsRows() bool {
+	for _, j := range c.joins {
+		if j.multi {
+			return true
+		}
+	}
+	return false
+}
+
+// multiValued reports whether path crosses a relation with many rows per base row
+func (c *compiler) multiValued(path string) bool {
+	_, multi, err := newCompiler(c.d, c.base).resolve(strings.Split(path, "__"), false)
+	return err == nil && multi
+}
+
+// nullable reports whether the field path can be NULL: the field allows
+// NULL or one of the relations it crosses may have no related row
+func (c *compiler) nullable(path string) bool {
+	for _, a := range c.annotations {
+		if a.name == path {
+			return false
+		}
+	}
+	meta := c.base
+	for _, name := range strings.Split(path, "__") {
+		if meta == nil {
+			return false
+		}
+		f := meta.field(name)
+		if f == nil {
+			return false
+		}
+		rel := f.relation()
+		if rel == nil {
+			return hasOption(f.Tag, "null") || f.Type.Kind() == reflect.Ptr
+		}
+		if rel.Nullable {
+			return true
+		}
+		meta = nil
+		if rel.Model != nil {
+			meta = metaOf(rel.Model)
+		}
+	}
+	return false
+}
+
+// subselect renders "id IN (SELECT id FROM base ... WHERE cond)" with cond
+// compiled by a fresh compiler, so the joins cond needs stay inside the
+// subquery instead of repeating rows of the outer query
+func (c *compiler) subselect(cond func(sub *compiler) string) string {
+	sub := newCompiler(c.d, c.base)
+	sub.args = c.args
+	if c.outer != nil {
+		// Within a subquery, OuterRef columns must not be shadowed
+		sub = c.nested(c.base)
+		sub.outer = c.outer
+	}
+	sub.annotations = c.annotations
+	where := cond(sub)
+	c.args = sub.args
+	if sub.err != nil {
+		c.fail(sub.err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s", sub.col("id"), aliased(c.base.Table, sub.alias), sub.joinSQL())
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return fmt.Sprintf("%s IN (%s)", c.col("id"), query)
+}
+
+func qualify(alias, column string) string {
+	if alias == "" {
+		return column
+	}
+	return alias + "." + column
+}
+
+func aliased(table, alias string) string {
+	if table == alias {
+		return table
+	}
+	return table + " " + alias
+}
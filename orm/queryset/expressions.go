@@ -0,0 +1,57 @@
+package queryset
+
+import "fmt"
+
+// Expression is a SQL fragment evaluated by the database, usable as a
+// filter value, in UpdateFields or as an annotation
+type Expression interface {
+	sql(c *compiler) string
+}
+
+// F references a column of the current row, or with "__" of a related row,
+// or an annotation, so comparisons and updates happen in the database rather
+// than on stale values read into Go:
+//
+//	qs.Filter(Q{"id": 1}).UpdateFields(map[string]interface{}{"views": F("views").Add(1)})
+type F string
+
+func (f F) sql(c *compiler) string { return c.ref(string(f)) }
+
+// Add returns f + v
+func (f F) Add(v interface{}) *Arithmetic { return arithmetic(f, "+", v) }
+
+// Sub returns f - v
+func (f F) Sub(v interface{}) *Arithmetic { return arithmetic(f, "-", v) }
+
+// Mul returns f * v
+func (f F) Mul(v interface{}) *Arithmetic { return arithmetic(f, "*", v) }
+
+// Div returns f / v
+func (f F) Div(v interface{}) *Arithmetic { return arithmetic(f, "/", v) }
+
+// Arithmetic combines two operands, each an Expression or a plain value
+type Arithmetic struct {
+	Left     interface{}
+	Operator string
+	Right    interface{}
+}
+
+func arithmetic(left interface{}, op string, right interface{}) *Arithmetic {
+	return &Arithmetic{Left: left, Operator: op, Right: right}
+}
+
+func (a *Arithmetic) sql(c *compiler) string {
+	return fmt.Sprintf("(%s %s %s)", c.value(a.Left), a.Operator, c.value(a.Right))
+}
+
+// Add returns a + v
+func (a *Arithmetic) Add(v interface{}) *Arithmetic { return arithmetic(a, "+", v) }
+
+// Sub returns a - v
+func (a *Arithmetic) Sub(v interface{}) *Arithmetic { return arithmetic(a, "-", v) }
+
+// Mul returns a * v
+func (a *Arithmetic) Mul(v interface{}) *Arithmetic { return arithmetic(a, "*", v) }
+
+// Div returns a / v
+func (a *Arithmetic) Div(v interface{}) *Arithmetic { return arithmetic(a, "/", v) }
@@ -0,0 +1,190 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Func calls a SQL function. String arguments name fields (or annotations)
+// of the current row, as in Aggregate; wrap literal strings in Value.
+// Expressions are rendered inline and other values are bound as arguments.
+//
+//	qs.Annotate(map[string]Expression{"initials": &Func{Function: "SUBSTR", Args: []interface{}{"name", 1, 1}}})
+type Func struct {
+	Function string
+	Args     []interface{}
+}
+
+func (f *Func) sql(c *compiler) string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = c.operand(arg)
+	}
+	return fmt.Sprintf("%s(%s)", f.Function, strings.Join(args, ", "))
+}
+
+// operand renders a function argument: a field path, an expression or a value
+func (c *compiler) operand(v interface{}) string {
+	if field, ok := v.(string); ok {
+		return c.ref(field)
+	}
+	return c.value(v)
+}
+
+// ValueExpression is a literal passed where a field name is expected
+type ValueExpression struct {
+	Value interface{}
+}
+
+// Value passes v as a literal, e.g. Concat("first_name", Value(" "), "last_name")
+func Value(v interface{}) *ValueExpression { return &ValueExpression{Value: v} }
+
+func (v *ValueExpression) sql(c *compiler) string { return c.param(v.Value) }
+
+// rawSQL is trusted SQL rendered as is
+type rawSQL string
+
+func (r rawSQL) sql(*compiler) string { return string(r) }
+
+// Lower converts a text field or expression to lowercase
+func Lower(field interface{}) *Func { return &Func{Function: "LOWER", Args: []interface{}{field}} }
+
+// Upper converts a text field or expression to uppercase
+func Upper(field interface{}) *Func { return &Func{Function: "UPPER", Args: []interface{}{field}} }
+
+// Coalesce returns the first of its arguments that is not NULL
+func Coalesce(args ...interface{}) *Func { return &Func{Function: "COALESCE", Args: args} }
+
+// Now returns the current date and time of the database
+func Now() Expression { return rawSQL("CURRENT_TIMESTAMP") }
+
+// ConcatExpression joins its arguments as text, treating NULL as empty
+type ConcatExpression struct {
+	Args []interface{}
+}
+
+// Concat joins fields, expressions and Values as text
+func Concat(args ...interface{}) *ConcatExpression { return &ConcatExpression{Args: args} }
+
+func (e *ConcatExpression) sql(c *compiler) string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = c.operand(arg)
+	}
+	return c.d.Concat(args)
+}
+
+// ExtractExpression reads a part of a date or time, like the transform of
+// the same name in lookups
+type ExtractExpression struct {
+	Field interface{}
+	Part  string
+}
+
+// Extract reads part ("year", "month", "week_day", "hour"...) of a date
+// field or expression
+func Extract(field interface{}, part string) *ExtractExpression {
+	return &ExtractExpression{Field: field, Part: part}
+}
+
+func (e *ExtractExpression) sql(c *compiler) string {
+	tmpl, ok := c.d.Transform(e.Part)
+	if !ok {
+		c.fail(fmt.Errorf("the %s backend cannot extract %q", c.d.Name(), e.Part))
+		return ""
+	}
+	return fmt.Sprintf(tmpl, c.operand(e.Field))
+}
+
+// CastExpression converts a value to a SQL type
+type CastExpression struct {
+	Field interface{}
+	Type  string
+}
+
+// Cast converts a field or expression to the SQL type sqlType, e.g. "INTEGER"
+func Cast(field interface{}, sqlType string) *CastExpression {
+	return &CastExpression{Field: field, Type: sqlType}
+}
+
+func (e *CastExpression) sql(c *compiler) string {
+	return fmt.Sprintf("CAST(%s AS %s)", c.operand(e.Field), e.Type)
+}
+
+// WhenExpression is a branch of a Case
+type WhenExpression struct {
+	Condition Node
+	Then      interface{}
+}
+
+// When returns then where condition matches. then is a value, or an
+// Expression such as F("title").
+func When(condition Node, then interface{}) *WhenExpression {
+	return &WhenExpression{Condition: condition, Then: then}
+}
+
+// CaseExpression picks the result of its first matching When
+type CaseExpression struct {
+	Whens   []*WhenExpression
+	Default interface{}
+}
+
+// Case evaluates whens in order, yielding NULL if none matches unless Else
+// sets a default:
+//
+//	Case(When(Q{"pages__gte": 300}, "long"), When(Q{"pages__gte": 100}, "medium")).Else("short")
+func Case(whens ...*WhenExpression) *CaseExpression {
+	return &CaseExpression{Whens: whens}
+}
+
+// Else sets the result when no When matches
+func (e *CaseExpression) Else(v interface{}) *CaseExpression {
+	cp := *e
+	cp.Default = v
+	return &cp
+}
+
+func (e *CaseExpression) sql(c *compiler) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, w := range e.Whens {
+		cond := c.compile(w.Condition)
+		if cond == "" {
+			cond = "1 = 1"
+		}
+		fmt.Fprintf(&b, " WHEN %s THEN %s", cond, c.value(w.Then))
+	}
+	if e.Default != nil {
+		fmt.Fprintf(&b, " ELSE %s", c.value(e.Default))
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// anyOperand reports whether pred holds for any operand of the expression v
+func anyOperand(v interface{}, pred func(interface{}) bool) bool {
+	var operands []interface{}
+	switch v := v.(type) {
+	case *Arithmetic:
+		operands = []interface{}{v.Left, v.Right}
+	case *Func:
+		operands = v.Args
+	case *ConcatExpression:
+		operands = v.Args
+	case *ExtractExpression:
+		operands = []interface{}{v.Field}
+	case *CastExpression:
+		operands = []interface{}{v.Field}
+	case *CaseExpression:
+		for _, w := range v.Whens {
+			operands = append(operands, w.Then)
+		}
+		operands = append(operands, v.Default)
+	}
+	for _, operand := range operands {
+		if pred(operand) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package queryset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFunctionSQL(t *testing.T) {
+	books := &QuerySet[*liteBook]{}
+
+	cases := []struct {
+		name     string
+		qs       *QuerySet[*liteBook]
+		expected string
+		args     []interface{}
+	}{
+		{
+			"scalar functions",
+			books.Annotate(map[string]Expression{
+				"label": Concat(Upper("title"), Value(": "), Coalesce("pages", 0)),
+				"year":  Extract(Now(), "year"),
+			}),
+			`SELECT lite_books.*, CONCAT(CAST(UPPER(lite_books.title) AS TEXT), CAST($1 AS TEXT), ` +
+				`CAST(COALESCE(lite_books.pages, $2) AS TEXT)) AS "label", ` +
+				`EXTRACT(YEAR FROM CURRENT_TIMESTAMP) AS "year" FROM lite_books`,
+			[]interface{}{": ", 0},
+		},
+		{
+			"concat of bound values",
+			books.Annotate(map[string]Expression{"name": Concat(Value("first"), Value(" "), "title")}),
+			`SELECT lite_books.*, CONCAT(CAST($1 AS TEXT), CAST($2 AS TEXT), CAST(lite_books.title AS TEXT)) AS "name" FROM lite_books`,
+			[]interface{}{"first", " "},
+		},
+		{
+			"filter and order by an annotation",
+			books.Annotate(map[string]Expression{"lower": Lower("title")}).Filter(Q{"lower__startswith": "s"}).OrderBy("-lower"),
+			`SELECT lite_books.*, LOWER(lite_books.title) AS "lower" FROM lite_books WHERE LOWER(lite_books.title) LIKE $1 ESCAPE '\' ORDER BY "lower" DESC`,
+			[]interface{}{"s%"},
+		},
+		{
+			"case",
+			books.Annotate(map[string]Expression{
+				"size": Case(When(Q{"pages__gte": 300}, "long"), When(Q{"pages__lt": 150}, F("title"))).Else("medium"),
+			}),
+			`SELECT lite_books.*, CASE WHEN lite_books.pages >= $1 THEN $2 WHEN lite_books.pages < $3 THEN lite_books.title ` +
+				`ELSE $4 END AS "size" FROM lite_books`,
+			[]interface{}{300, "long", 150, "medium"},
+		},
+		{
+			"window",
+			books.Annotate(map[string]Expression{
+				"row":   Window(RowNumber(), []string{"author_id"}, []string{"-pages", "id"}),
+				"total": Window(Sum("pages"), nil, []string{"id"}),
+			}),
+			`SELECT lite_books.*, ROW_NUMBER() OVER (PARTITION BY lite_books.author_id ORDER BY lite_books.pages DESC, lite_books.id) AS "row", ` +
+				`SUM(lite_books.pages) OVER (ORDER BY lite_books.id) AS "total" FROM lite_books`,
+			nil,
+		},
+		{
+			"filter on a window",
+			books.Annotate(map[string]Expression{"row": Window(RowNumber(), []string{"author_id"}, []string{"-pages"})}).
+				Filter(Q{"row": 1, "pages__gt": 50}).OrderBy("title").Limit(10),
+			`SELECT * FROM (SELECT lite_books.*, ROW_NUMBER() OVER (PARTITION BY lite_books.author_id ORDER BY lite_books.pages DESC) AS "row" ` +
+				`FROM lite_books WHERE lite_books.pages > $1) AS windowed WHERE windowed."row" = $2 ORDER BY windowed.title LIMIT 10`,
+			[]interface{}{50, 1},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sql, args, err := tc.qs.SQL()
+			if err != nil || sql != tc.expected {
+				t.Errorf("\n got %q, %v\nwant %q", sql, err, tc.expected)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("expected args %v, got %v", tc.args, args)
+			}
+		})
+	}
+
+	sql, _, _ := (&QuerySet[*liteAuthor]{}).Annotate(map[string]Expression{"book_count": Count("books")}).
+		Filter(Q{"book_count__gte": 2, "name__ne": "Bob"}).SQL()
+	expected := `SELECT lite_authors.*, COUNT(lite_books.id) AS "book_count" FROM lite_authors ` +
+		`LEFT JOIN lite_books ON lite_authors.id = lite_books.author_id WHERE lite_authors.name <> $1 ` +
+		`GROUP BY lite_authors.id HAVING COUNT(lite_books.id) >= $2`
+	if sql != expected {
+		t.Errorf("filter on an aggregate:\n got %q\nwant %q", sql, expected)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database)
+
+	t.Run("scalar functions and conditionals", func(t *testing.T) {
+		rows, err := books.Values("title").Annotate(map[string]Expression{
+			"shout": Concat(Upper("title"), Value("!")),
+			"quiet": Lower("title"),
+			"size":  Case(When(Q{"pages__gte": 300}, "long"), When(Q{"pages__gte": 200}, "medium")).Else("short"),
+			"half":  Cast(F("pages").Div(2), "INTEGER"),
+			"when":  Coalesce(nil, Extract(Value("2024-05-01"), "month")),
+		}).Filter(Q{"quiet__ne": "rust"}).OrderBy("-size").All()
+		if err != nil {
+			t.Fatalf("Values failed: %v", err)
+		}
+		expected := []map[string]interface{}{
+			{"title": "Go", "shout": "GO!", "quiet": "go", "size": "short", "half": int64(50), "when": int64(5)},
+			{"title": "SQL", "shout": "SQL!", "quiet": "sql", "size": "long", "half": int64(150), "when": int64(5)},
+		}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected %v, got %v", expected, rows)
+		}
+	})
+
+	t.Run("latest per group", func(t *testing.T) {
+		longest := books.Annotate(map[string]Expression{
+			"row": Window(RowNumber(), []string{"author_id"}, []string{"-pages"}),
+		}).Filter(Q{"row": 1}).OrderBy("id")
+		results, err := longest.All()
+		if err != nil {
+			t.Fatalf("All failed: %v", err)
+		}
+		if len(results) != 2 || results[0].Title != "SQL" || results[1].Title != "Rust" {
+			t.Errorf("expected each author's longest book, got %+v", results)
+		}
+		if n, err := longest.Count(); err != nil || n != 2 {
+			t.Errorf("expected a count of 2, got %d, %v", n, err)
+		}
+		if ok, err := longest.Filter(Q{"row__gt": 1}).Exists(); err != nil || ok {
+			t.Errorf("expected the window filters to apply to Exists, got %v, %v", ok, err)
+		}
+
+		var titles []string
+		for b, err := range longest.OrderBy().Iterator(1) {
+			if err != nil {
+				t.Fatalf("Iterator failed: %v", err)
+			}
+			titles = append(titles, b.Title)
+		}
+		if len(titles) != 2 {
+			t.Errorf("expected the iterator to rank all rows, got %v", titles)
+		}
+	})
+
+	t.Run("ranking and offsets", func(t *testing.T) {
+		rows, err := books.Values("title").Annotate(map[string]Expression{
+			"rank":    Window(Rank(), nil, []string{"-pages"}),
+			"before":  Window(Lag("title", 1), nil, []string{"pages"}),
+			"after":   Window(Lead("title", 1), nil, []string{"pages"}),
+			"running": Window(Sum("pages"), []string{"author_id"}, []string{"id"}),
+		}).OrderBy("rank").All()
+		if err != nil {
+			t.Fatalf("Values failed: %v", err)
+		}
+		expected := []map[string]interface{}{
+			{"title": "SQL", "rank": int64(1), "before": "Rust", "after": nil, "running": int64(400)},
+			{"title": "Rust", "rank": int64(2), "before": "Go", "after": "SQL", "running": int64(200)},
+			{"title": "Go", "rank": int64(3), "before": nil, "after": "Rust", "running": int64(100)},
+		}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected %v, got %v", expected, rows)
+		}
+	})
+
+	t.Run("filters on aggregates", func(t *testing.T) {
+		authors := NewQuerySet[*liteAuthor](database).Annotate(map[string]Expression{"book_count": Count("books")})
+		prolific, err := authors.Filter(Q{"book_count__gte": 1}).Exclude(Q{"name": "Bob"}).All()
+		if err != nil {
+			t.Fatalf("All failed: %v", err)
+		}
+		if len(prolific) != 1 || prolific[0].Name != "Ann" || prolific[0].BookCount != 2 {
+			t.Errorf("expected Ann with 2 books, got %+v", prolific)
+		}
+		if n, err := authors.Filter(Q{"book_count": 0}).Count(); err != nil || n != 1 {
+			t.Errorf("expected one author without books, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := books.Annotate(map[string]Expression{"row": Window(RowNumber(), nil, []string{"id"})}).
+			Filter(Q{"row": 1}).Aggregate(map[string]Expression{"pages": Sum("pages")})
+		if err == nil || !strings.Contains(err.Error(), "window") {
+			t.Errorf("expected an error aggregating over a window filter, got %v", err)
+		}
+		_, err = books.Annotate(map[string]Expression{"day": Extract("title", "fortnight")}).All()
+		if err == nil || !strings.Contains(err.Error(), "fortnight") {
+			t.Errorf("expected an error for an unknown part, got %v", err)
+		}
+	})
+}
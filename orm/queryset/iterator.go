@@ -0,0 +1,196 @@
+package queryset
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"sync/atomic"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// defaultChunkSize is the number of rows Iterator fetches at a time when
+// no chunk size is given
+const defaultChunkSize = 2000
+
+var cursorSeq atomic.Uint64
+
+// Iterator streams the queryset's rows, holding at most chunkSize of them in
+// memory. Databases with server-side cursors read through one in a
+// transaction; others fetch chunks by primary key (or by offset, when the
+// queryset is ordered, sliced or computes window functions) and release the
+// connection between chunks. Offset chunks are ordered by the primary key
+// after the queryset's own ordering, so rows sorting equal are neither
+// skipped nor repeated.
+// PrefetchRelated runs once per chunk.
+//
+//	for user, err := range qs.Iterator(1000) {
+//		if err != nil {
+//			return err
+//		}
+//		export(user)
+//	}
+func (q *QuerySet[T]) Iterator(chunkSize int) iter.Seq2[T, error] {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		q, err := q.resolve(false)
+		if err == nil {
+			err = q.checkLocking()
+		}
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		var next func() ([]T, error)
+		switch {
+		case q.db.Ops().SupportsCursors():
+			query, args, err := q.compile(nil)
+			if err == nil {
+				err = q.iterateCursor(query, args, chunkSize, yield)
+			}
+			if err != nil {
+				yield(zero, err)
+			}
+			return
+		case q.limit == 0 && q.offset == 0 && q.combined == nil && !q.windowed() && keysetOrder(q.ordering) != "":
+			next = q.keysetChunks(chunkSize)
+		default:
+			next = q.offsetChunks(chunkSize)
+		}
+
+		for {
+			chunk, err := next()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, obj := range chunk {
+				if !yield(obj, nil) {
+					return
+				}
+			}
+			if len(chunk) < chunkSize {
+				return
+			}
+		}
+	}
+}
+
+// keysetOrder returns the primary key ordering ("id" or "-id") chunks can
+// page by, or "" if ordering sorts by anything else
+func keysetOrder(ordering []string) string {
+	switch {
+	case len(ordering) == 0:
+		return "id"
+	case len(ordering) == 1 && (ordering[0] == "id" || ordering[0] == "-id"):
+		return ordering[0]
+	}
+	return ""
+}
+
+// tieBroken appends the primary key to ordering unless it already sorts by
+// it, which makes the order of every row deterministic
+func tieBroken(ordering []string) []string {
+	for _, field := range ordering {
+		if strings.TrimPrefix(field, "-") == "id" {
+			return ordering
+		}
+	}
+	return append(append([]string(nil), ordering...), "id")
+}
+
+// keysetChunks returns a function fetching the next chunk of rows past the
+// last primary key seen
+func (q *QuerySet[T]) keysetChunks(chunkSize int) func() ([]T, error) {
+	order := keysetOrder(q.ordering)
+	after := "id__gt"
+	if order == "-id" {
+		after = "id__lt"
+	}
+
+	var last interface{}
+	return func() ([]T, error) {
+		chunk := q.OrderBy(order).Limit(chunkSize)
+		if last != nil {
+			chunk = chunk.Filter(Q{after: last})
+		}
+		results, err := chunk.All()
+		if err != nil || len(results) == 0 {
+			return results, err
+		}
+		last = primaryKey(structValue(results[len(results)-1]))
+		return results, nil
+	}
+}
+
+// offsetChunks returns a function fetching the next chunk of rows by
+// offset, staying within the queryset's own slice
+func (q *QuerySet[T]) offsetChunks(chunkSize int) func() ([]T, error) {
+	ordered := q.OrderBy(tieBroken(q.ordering)...)
+	offset, remaining := q.offset, q.limit
+	return func() ([]T, error) {
+		size := chunkSize
+		if q.limit > 0 {
+			if remaining <= 0 {
+				return nil, nil
+			}
+			size = min(size, remaining)
+		}
+
+		results, err := ordered.Offset(offset).Limit(size).All()
+		offset += len(results)
+		remaining -= len(results)
+		return results, err
+	}
+}
+
+// iterateCursor yields the rows of query fetched chunkSize at a time from a
+// server-side cursor, which only lives as long as its transaction
+func (q *QuerySet[T]) iterateCursor(query string, args []interface{}, chunkSize int, yield func(T, error) bool) error {
+	ctx := q.getContext()
+	return q.db.Atomic(ctx, func(tx *db.DB) error {
+		cursor := fmt.Sprintf("queryset_cursor_%d", cursorSeq.Add(1))
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursor, query), args...); err != nil {
+			return err
+		}
+		defer tx.ExecContext(ctx, "CLOSE "+cursor)
+
+		scoped := q.WithTx(tx)
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", chunkSize, cursor)
+		for {
+			chunk, err := scoped.fetch(fetch)
+			if err != nil {
+				return err
+			}
+			for _, obj := range chunk {
+				if !yield(obj, nil) {
+					return nil
+				}
+			}
+			if len(chunk) < chunkSize {
+				return nil
+			}
+		}
+	})
+}
+
+// fetch runs query, such as a FETCH, and prefetches relations for the
+// rows it returns
+func (q *QuerySet[T]) fetch(query string, args ...interface{}) ([]T, error) {
+	rows, err := q.db.QueryContext(q.getContext(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := scanRows[T](rows)
+	// The prefetch queries run on the same transaction
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	return chunk, q.prefetch(chunk)
+}
@@ -0,0 +1,76 @@
+package queryset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db/dbtest"
+)
+
+func TestIterator(t *testing.T) {
+	database := newShelfDB(t)
+	authors := NewQuerySet[*liteShelfAuthor](database)
+
+	collect := func(qs *QuerySet[*liteShelfAuthor], chunkSize int) []string {
+		t.Helper()
+		var names []string
+		for a, err := range qs.Iterator(chunkSize) {
+			if err != nil {
+				t.Fatalf("Iterator failed: %v", err)
+			}
+			names = append(names, a.Name)
+		}
+		return names
+	}
+
+	t.Run("fetches keyset chunks and prefetches each", func(t *testing.T) {
+		var books []int
+		events := dbtest.AssertNumQueries(t, 4, func() {
+			for a, err := range authors.PrefetchRelated("books").Iterator(2) {
+				if err != nil {
+					t.Fatalf("Iterator failed: %v", err)
+				}
+				books = append(books, len(a.Books))
+			}
+		})
+		if !reflect.DeepEqual(books, []int{2, 1, 0}) {
+			t.Errorf("expected Ann, Bob and Cid's books, got %v", books)
+		}
+		if !strings.Contains(events[2].SQL, "lite_authors.id > ?1") {
+			t.Errorf("expected the second chunk to start after the last id, got %q", events[2].SQL)
+		}
+	})
+
+	t.Run("pages by offset when ordered by other fields", func(t *testing.T) {
+		if names := collect(authors.OrderBy("-name"), 2); !reflect.DeepEqual(names, []string{"Cid", "Bob", "Ann"}) {
+			t.Errorf("unexpected order %v", names)
+		}
+		if names := collect(authors.OrderBy("-id"), 1); !reflect.DeepEqual(names, []string{"Cid", "Bob", "Ann"}) {
+			t.Errorf("unexpected order %v", names)
+		}
+	})
+
+	t.Run("breaks offset ties by primary key", func(t *testing.T) {
+		events := dbtest.AssertNumQueries(t, 2, func() {
+			collect(authors.OrderBy("-name"), 2)
+		})
+		if !strings.Contains(events[0].SQL, "ORDER BY lite_authors.name DESC, lite_authors.id") {
+			t.Errorf("expected the primary key to break ties, got %q", events[0].SQL)
+		}
+	})
+
+	t.Run("stays within a slice", func(t *testing.T) {
+		if names := collect(authors.OrderBy("name").Offset(1).Limit(1), 5); !reflect.DeepEqual(names, []string{"Bob"}) {
+			t.Errorf("expected only Bob, got %v", names)
+		}
+	})
+
+	t.Run("stops when the loop breaks", func(t *testing.T) {
+		dbtest.AssertNumQueries(t, 1, func() {
+			for range authors.Iterator(1) {
+				break
+			}
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package queryset
+
+import (
+	"fmt"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// ForUpdate configures the row locks taken by SelectForUpdate
+type ForUpdate struct {
+	// NoWait fails the query instead of waiting for rows locked by another
+	// transaction
+	NoWait bool
+	// SkipLocked leaves rows locked by another transaction out of the
+	// results, e.g. so several workers can take jobs from one queue
+	SkipLocked bool
+	// Of locks only the rows of these relation paths, with "self" for the
+	// queryset's own model, when the query joins other tables
+	Of []string
+}
+
+// SelectForUpdate locks the selected rows until the end of the
+// transaction, with SELECT ... FOR UPDATE. The queryset must run in a
+// transaction; SQLite, which locks the whole database for writes, ignores
+// the locks.
+//
+//	err := database.Atomic(ctx, func(tx *db.DB) error {
+//		jobs, err := NewQuerySet[*Job](tx).Filter(Q{"done": false}).
+//			SelectForUpdate(ForUpdate{SkipLocked: true}).Limit(10).All()
+//		...
+//	})
+func (q *QuerySet[T]) SelectForUpdate(opts ForUpdate) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.forUpdate = &opts
+	return newQs
+}
+
+// lockClause renders q's FOR UPDATE clause, resolving Of to the aliases of
+// tables c has joined
+func (q *QuerySet[T]) lockClause(c *compiler) string {
+	if q.forUpdate == nil {
+		return ""
+	}
+	if q.forUpdate.NoWait && q.forUpdate.SkipLocked {
+		c.fail(fmt.Errorf("select for update cannot both skip locked rows and not wait"))
+		return ""
+	}
+
+	var of []string
+	for _, path := range q.forUpdate.Of {
+		alias := ""
+		if path == "self" {
+			alias = c.alias
+		}
+		for _, j := range c.joins {
+			if j.path == path {
+				alias = j.alias
+			}
+		}
+		if alias == "" {
+			c.fail(fmt.Errorf("select for update of %q: the query doesn't join that relation", path))
+			return ""
+		}
+		of = append(of, alias)
+	}
+	return c.d.ForUpdate(of, q.forUpdate.NoWait, q.forUpdate.SkipLocked)
+}
+
+// checkLocking rejects locking outside a transaction, where the locks would
+// be released as soon as the query returns
+func (q *QuerySet[T]) checkLocking() error {
+	if q.forUpdate != nil && !q.db.InTransaction() {
+		return fmt.Errorf("select for update: %w", db.ErrNotInTransaction)
+	}
+	return nil
+}
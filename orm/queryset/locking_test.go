@@ -0,0 +1,90 @@
+package queryset
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+func TestSelectForUpdateSQL(t *testing.T) {
+	novels := &QuerySet[*liteNovel]{}
+
+	tests := []struct {
+		name     string
+		sql      func() (string, []interface{}, error)
+		expected string
+	}{
+		{
+			name:     "locks after the slice",
+			sql:      novels.Filter(Q{"pages__gt": 100}).SelectForUpdate(ForUpdate{}).Limit(10).SQL,
+			expected: "SELECT lite_books.* FROM lite_books WHERE lite_books.pages > $1 LIMIT 10 FOR UPDATE",
+		},
+		{
+			name:     "skip locked",
+			sql:      novels.SelectForUpdate(ForUpdate{SkipLocked: true}).SQL,
+			expected: "SELECT lite_books.* FROM lite_books FOR UPDATE SKIP LOCKED",
+		},
+		{
+			name:     "nowait",
+			sql:      novels.SelectForUpdate(ForUpdate{NoWait: true}).SQL,
+			expected: "SELECT lite_books.* FROM lite_books FOR UPDATE NOWAIT",
+		},
+		{
+			name: "of joined tables",
+			sql:  (&QuerySet[*liteBook]{}).Filter(Q{"author__name": "Ann"}).SelectForUpdate(ForUpdate{Of: []string{"self", "author"}}).SQL,
+			expected: "SELECT lite_books.* FROM lite_books INNER JOIN lite_authors ON lite_books.author_id = lite_authors.id " +
+				"WHERE lite_authors.name = $1 FOR UPDATE OF lite_books, lite_authors",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql, _, err := tt.sql(); err != nil || sql != tt.expected {
+				t.Errorf("\n got %q, %v\nwant %q", sql, err, tt.expected)
+			}
+		})
+	}
+
+	if sql, _, _ := novels.SelectForUpdate(ForUpdate{}).countSQL(nil); strings.Contains(sql, "FOR UPDATE") {
+		t.Errorf("expected Count to take no locks, got %q", sql)
+	}
+	if _, _, err := novels.SelectForUpdate(ForUpdate{Of: []string{"author"}}).compile(nil); err == nil {
+		t.Error("expected an error locking a relation the query doesn't join")
+	}
+	if _, _, err := novels.SelectForUpdate(ForUpdate{NoWait: true, SkipLocked: true}).compile(nil); err == nil {
+		t.Error("expected an error combining NoWait and SkipLocked")
+	}
+}
+
+func TestSelectForUpdate(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database).SelectForUpdate(ForUpdate{})
+
+	if _, err := books.All(); !errors.Is(err, db.ErrNotInTransaction) {
+		t.Errorf("expected ErrNotInTransaction outside a transaction, got %v", err)
+	}
+	for _, err := range books.Iterator(10) {
+		if !errors.Is(err, db.ErrNotInTransaction) {
+			t.Errorf("expected Iterator to fail outside a transaction, got %v", err)
+		}
+		break
+	}
+
+	err := database.Atomic(context.Background(), func(tx *db.DB) error {
+		locked, err := books.WithTx(tx).Filter(Q{"pages__gt": 250}).All()
+		if err != nil {
+			return err
+		}
+		if len(locked) != 1 {
+			t.Errorf("expected 1 locked book, got %d", len(locked))
+		}
+		_, err = books.WithTx(tx).Count()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("locking in a transaction failed: %v", err)
+	}
+}
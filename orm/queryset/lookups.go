@@ -0,0 +1,97 @@
+package queryset
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// Lookup is a comparison registered under a name with RegisterLookup, used
+// as the last part of a filter key:
+//
+//	queryset.RegisterLookup("search", queryset.Lookup{
+//		SQL: func(d db.Dialect, column, value string) string {
+//			return fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(%s)", column, value)
+//		},
+//	})
+//	qs.Filter(Q{"body__search": "go orm"})
+type Lookup struct {
+	// SQL renders the condition from the column, after any transforms, and
+	// the value's placeholder or, for an Expression, its SQL
+	SQL func(d db.Dialect, column, value string) string
+	// Prepare optionally rewrites a plain value before it is bound
+	Prepare func(v interface{}) interface{}
+}
+
+// Transform turns a column into the value lookups compare, e.g. the year
+// of a date. Transforms chain: "created_at__date__gte".
+//
+//	queryset.RegisterTransform("lower", func(d db.Dialect, column string) string {
+//		return "LOWER(" + column + ")"
+//	})
+type Transform func(d db.Dialect, column string) string
+
+// Built-in lookups the compiler renders itself; the rest come from the
+// dialect's operators (exact, iexact, ne, contains, startswith, regex, gt...)
+// and transforms (year, month, day, week_day, date, hour...)
+var structuralLookups = map[string]bool{"in": true, "isnull": true, "range": true}
+
+var (
+	registryMu sync.RWMutex
+	lookups    = make(map[string]Lookup)
+	transforms = make(map[string]Transform)
+)
+
+// RegisterLookup makes a lookup available to every queryset, overriding a
+// built-in lookup of the same name
+func RegisterLookup(name string, l Lookup) {
+	if l.SQL == nil {
+		panic(fmt.Sprintf("queryset: lookup %q has no SQL", name))
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	lookups[name] = l
+}
+
+// RegisterTransform makes a transform available to every queryset,
+// overriding a built-in transform of the same name
+func RegisterTransform(name string, t Transform) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	transforms[name] = t
+}
+
+func registeredLookup(name string) (Lookup, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	l, ok := lookups[name]
+	return l, ok
+}
+
+func (c *compiler) isLookup(name string) bool {
+	if _, ok := registeredLookup(name); ok || structuralLookups[name] {
+		return true
+	}
+	_, ok := c.d.Operator(name)
+	return ok
+}
+
+func (c *compiler) isTransform(name string) bool {
+	return c.transform(name) != nil
+}
+
+// transform returns the registered or dialect transform called name, or nil
+func (c *compiler) transform(name string) Transform {
+	registryMu.RLock()
+	t, ok := transforms[name]
+	registryMu.RUnlock()
+	if ok {
+		return t
+	}
+
+	if tmpl, ok := c.d.Transform(name); ok {
+		return func(_ db.Dialect, column string) string { return fmt.Sprintf(tmpl, column) }
+	}
+	return nil
+}
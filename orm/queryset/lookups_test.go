@@ -0,0 +1,223 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+func TestRelationLookupSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      func() (string, []interface{}, error)
+		expected string
+	}{
+		{
+			name:     "forward foreign key",
+			sql:      (&QuerySet[*liteBook]{}).Filter(Q{"author__name__icontains": "ann"}).SQL,
+			expected: "SELECT lite_books.* FROM lite_books INNER JOIN lite_authors ON lite_books.author_id = lite_authors.id WHERE lite_authors.name ILIKE $1 ESCAPE '\\'",
+		},
+		{
+			name:     "foreign key itself needs no join",
+			sql:      (&QuerySet[*liteBook]{}).Filter(Q{"author": 1}).SQL,
+			expected: "SELECT lite_books.* FROM lite_books WHERE lite_books.author_id = $1",
+		},
+		{
+			name:     "reverse relation is distinct",
+			sql:      (&QuerySet[*liteAuthor]{}).Filter(Q{"books__pages__gt": 150}).SQL,
+			expected: "SELECT DISTINCT lite_authors.* FROM lite_authors LEFT JOIN lite_books ON lite_authors.id = lite_books.author_id WHERE lite_books.pages > $1",
+		},
+		{
+			name:     "many to many through table",
+			sql:      (&QuerySet[MockUser]{}).Filter(Q{"followers__username": "ann"}).SQL,
+			expected: "SELECT DISTINCT mock_users.* FROM mock_users LEFT JOIN user_follows ON user_follows.following_id = mock_users.id LEFT JOIN mock_users mock_users2 ON mock_users2.id = user_follows.follower_id WHERE mock_users2.username = $1",
+		},
+		{
+			name:     "exclude across a reverse relation uses a subquery",
+			sql:      (&QuerySet[*liteAuthor]{}).Exclude(Q{"books__title": "Go"}).SQL,
+			expected: "SELECT lite_authors.* FROM lite_authors WHERE NOT (lite_authors.id IN (SELECT lite_authors.id FROM lite_authors LEFT JOIN lite_books ON lite_authors.id = lite_books.author_id WHERE lite_books.title = $1))",
+		},
+		{
+			name:     "ordering across a relation",
+			sql:      (&QuerySet[*liteBook]{}).OrderBy("author__name", "-pages").SQL,
+			expected: "SELECT lite_books.* FROM lite_books LEFT JOIN lite_authors ON lite_books.author_id = lite_authors.id ORDER BY lite_authors.name, lite_books.pages DESC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql, _, err := tt.sql(); err != nil || sql != tt.expected {
+				t.Errorf("\n got %q, %v\nwant %q", sql, err, tt.expected)
+			}
+		})
+	}
+
+	if _, err := NewQuerySet[*liteBook](newSQLiteDB(t)).Filter(Q{"title__name": "x"}).All(); err == nil || !strings.Contains(err.Error(), "not a relation") {
+		t.Errorf("expected an error for a lookup through a plain field, got %v", err)
+	}
+}
+
+func TestRelationLookups(t *testing.T) {
+	database := newLibraryDB(t)
+	authors := NewQuerySet[*liteAuthor](database)
+	books := NewQuerySet[*liteBook](database)
+
+	names := func(qs *QuerySet[*liteAuthor]) string {
+		t.Helper()
+		results, err := qs.OrderBy("name").All()
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		var names []string
+		for _, a := range results {
+			names = append(names, a.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := names(authors.Filter(Q{"books__pages__gte": 100})); got != "Ann,Bob" {
+		t.Errorf("reverse filter: got %q", got)
+	}
+	if got := names(authors.Exclude(Q{"books__title": "Go"})); got != "Bob,Cid" {
+		t.Errorf("reverse exclude: got %q", got)
+	}
+	if n, err := authors.Filter(Q{"books__pages__gte": 100}).Count(); err != nil || n != 2 {
+		t.Errorf("expected to count 2 authors once each, got %d (%v)", n, err)
+	}
+
+	if n, err := books.Filter(Q{"author__name": "Ann"}).Count(); err != nil || n != 2 {
+		t.Errorf("forward filter: got %d (%v)", n, err)
+	}
+
+	updated, err := books.Filter(Q{"author__name": "Ann"}).UpdateFields(map[string]interface{}{"pages": F("pages").Add(1)})
+	if err != nil || updated != 2 {
+		t.Fatalf("UpdateFields across a relation: %d (%v)", updated, err)
+	}
+	stats, _ := books.Aggregate(map[string]Expression{"total": Sum("pages")})
+	if stats["total"] != int64(602) {
+		t.Errorf("expected 602 pages after the update, got %v", stats["total"])
+	}
+}
+
+func TestFieldLookups(t *testing.T) {
+	database := newSQLiteDB(t)
+	if err := database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL",
+		"published":  "BOOLEAN NOT NULL",
+		"created_at": "TIMESTAMP WITH TIME ZONE",
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	qs := NewQuerySet[*liteArticle](database)
+	for i, title := range []string{"Go Generics", "SQLite Tips", "Postgres Tuning"} {
+		if err := qs.Create(&liteArticle{Title: title, Views: int64(i * 10)}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if _, err := database.Exec("UPDATE lite_articles SET created_at = NULL WHERE id = 3"); err != nil {
+		t.Fatalf("clearing created_at failed: %v", err)
+	}
+	if _, err := database.Exec("UPDATE lite_articles SET created_at = '2023-12-31 23:30:00' WHERE id = 2"); err != nil {
+		t.Fatalf("backdating failed: %v", err)
+	}
+
+	titles := func(filter Q) string {
+		t.Helper()
+		results, err := qs.Filter(filter).OrderBy("id").All()
+		if err != nil {
+			t.Fatalf("Filter(%v) failed: %v", filter, err)
+		}
+		var titles []string
+		for _, a := range results {
+			titles = append(titles, a.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	tests := []struct {
+		filter   Q
+		expected string
+	}{
+		{Q{"title__startswith": "SQL"}, "SQLite Tips"},
+		{Q{"title__iendswith": "TUNING"}, "Postgres Tuning"},
+		{Q{"views__ne": 10}, "Go Generics,Postgres Tuning"},
+		{Q{"views__range": []int64{5, 20}}, "SQLite Tips,Postgres Tuning"},
+		{Q{"created_at__isnull": true}, "Postgres Tuning"},
+		{Q{"title__regex": `^[A-Z]\w+ T`}, "SQLite Tips,Postgres Tuning"},
+		{Q{"title__iregex": "generics$"}, "Go Generics"},
+		{Q{"created_at__year": 2023}, "SQLite Tips"},
+		{Q{"created_at__month__gte": 12}, "SQLite Tips"},
+		{Q{"created_at__date": "2023-12-31"}, "SQLite Tips"},
+		{Q{"created_at__hour": 23, "created_at__minute": 30}, "SQLite Tips"},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.filter); got != tt.expected {
+			t.Errorf("Filter(%v): got %q, want %q", tt.filter, got, tt.expected)
+		}
+	}
+
+	t.Run("unknown lookups fail", func(t *testing.T) {
+		for _, key := range []string{"title__sounds_like", "title__year__sounds_like"} {
+			if _, err := qs.Filter(Q{key: "x"}).All(); err == nil || !strings.Contains(err.Error(), `unsupported lookup "sounds_like"`) {
+				t.Errorf("%s: expected an unsupported lookup error, got %v", key, err)
+			}
+		}
+		if _, err := qs.Filter(Q{"created_at__week": 1}).All(); err == nil {
+			t.Error("expected the week transform to be unsupported on sqlite")
+		}
+	})
+
+	t.Run("registered lookups and transforms", func(t *testing.T) {
+		RegisterTransform("length", func(d db.Dialect, column string) string {
+			return "LENGTH(" + column + ")"
+		})
+		RegisterLookup("divisible_by", Lookup{
+			SQL: func(d db.Dialect, column, value string) string {
+				return fmt.Sprintf("%s %% %s = 0", column, value)
+			},
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			delete(transforms, "length")
+			delete(lookups, "divisible_by")
+			registryMu.Unlock()
+		})
+
+		if got := titles(Q{"title__length__divisible_by": 5}); got != "Postgres Tuning" {
+			t.Errorf("got %q", got)
+		}
+		if got := titles(Q{"title__length__lt": 12}); got != "Go Generics,SQLite Tips" {
+			t.Errorf("got %q", got)
+		}
+	})
+	t.Run("wildcards match literally", func(t *testing.T) {
+		_, args, _ := qs.Filter(Q{"title__contains": `50%_\`}).SQL()
+		if len(args) != 1 || args[0] != `%50\%\_\\%` {
+			t.Errorf("expected the wildcards to be escaped, got %v", args)
+		}
+
+		for _, title := range []string{"50% Off", "500 Days", "a_b", "axb", `C:\Go`} {
+			if err := qs.Create(&liteArticle{Title: title}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+		}
+		tests := []struct {
+			filter   Q
+			expected string
+		}{
+			{Q{"title__startswith": "50%"}, "50% Off"},
+			{Q{"title__contains": "a_b"}, "a_b"},
+			{Q{"title__iexact": "A_B"}, "a_b"},
+			{Q{"title__endswith": `:\Go`}, `C:\Go`},
+		}
+		for _, tt := range tests {
+			if got := titles(tt.filter); got != tt.expected {
+				t.Errorf("Filter(%v): got %q, want %q", tt.filter, got, tt.expected)
+			}
+		}
+	})
+}
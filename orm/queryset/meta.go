@@ -0,0 +1,230 @@
+package queryset
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/anuragcarret/djang-drf-go/core/apps"
+)
+
+// fieldMeta describes a model field as seen by the query compiler
+type fieldMeta struct {
+	Name   string
+	Column string
+	Tag    string
+	Index  []int
+	Type   reflect.Type
+}
+
+// modelMeta describes a model's table and fields, including embedded ones
+type modelMeta struct {
+	Type   reflect.Type
+	Table  string
+	Fields []*fieldMeta
+}
+
+var metaCache sync.Map // reflect.Type -> *modelMeta
+
+// metaOf returns the metadata of a model type (struct or pointer to struct)
+func metaOf(t reflect.Type) *modelMeta {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if m, ok := metaCache.Load(t); ok {
+		return m.(*modelMeta)
+	}
+
+	m := &modelMeta{Type: t}
+	if model, ok := reflect.New(t).Interface().(ModelInterface); ok {
+		m.Table = model.TableName()
+	}
+	collectMeta(t, nil, m)
+
+	actual, _ := metaCache.LoadOrStore(t, m)
+	return actual.(*modelMeta)
+}
+
+func collectMeta(t reflect.Type, index []int, m *modelMeta) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			collectMeta(f.Type, idx, m)
+			continue
+		}
+
+		// Fields without a drf tag are not stored, as in collectFields and
+		// the migrations autodetector
+		tag := f.Tag.Get("drf")
+		if tag == "" || tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		column := strings.Split(tag, ";")[0]
+		if column == "" || strings.Contains(column, "=") || isTagOption(column) {
+			column = toSnakeCase(f.Name)
+		}
+		m.Fields = append(m.Fields, &fieldMeta{Name: f.Name, Column: column, Tag: tag, Index: idx, Type: f.Type})
+	}
+}
+
+// field finds a field by column name or Go name (case-insensitive). A
+// foreign key column "author_id" can also be named "author", as in Django.
+func (m *modelMeta) field(name string) *fieldMeta {
+	for _, f := range m.Fields {
+		if f.Column == name {
+			return f
+		}
+	}
+	for _, f := range m.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	for _, f := range m.Fields {
+		if f.Column == name+"_id" && (hasOption(f.Tag, "foreign_key") || hasOption(f.Tag, "one_to_one")) {
+			return f
+		}
+	}
+	return nil
+}
+
+// columns returns the fields stored as columns of the model's table
+func (m *modelMeta) columns() []*fieldMeta {
+	var cols []*fieldMeta
+	for _, f := range m.Fields {
+		if hasOption(f.Tag, "relation") || hasOption(f.Tag, "m2m") || hasOption(f.Tag, "annotation") {
+			continue
+		}
+		cols = append(cols, f)
+	}
+	return cols
+}
+
+// relation describes how to join from a model to a related table
+type relation struct {
+	// Table is the related table
+	Table string
+	// LocalColumn on the current table joins RemoteColumn on Table, unless
+	// Through is set for many-to-many relations
+	LocalColumn  string
+	RemoteColumn string
+	// Through, ThroughFrom and ThroughTo describe an m2m join table:
+	// Through.ThroughFrom references the current row, Through.ThroughTo the related row
+	Through     string
+	ThroughFrom string
+	ThroughTo   string
+	// Multi is true for relations that can match several rows
+	Multi bool
+	// Nullable is true when the current row may have no related row
+	Nullable bool
+	// Model is the related model type if known
+	Model reflect.Type
+}
+
+// relation returns how f joins to a related table, or nil if f is a plain column
+func (f *fieldMeta) relation() *relation {
+	if fk := getOptionValue(f.Tag, "foreign_key"); fk != "" {
+		return f.forwardRelation(fk)
+	}
+	if o2o := getOptionValue(f.Tag, "one_to_one"); o2o != "" {
+		return f.forwardRelation(o2o)
+	}
+
+	elem := f.Type
+	for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	if rel := getOptionValue(f.Tag, "relation"); rel != "" {
+		parts := strings.Split(rel, ".")
+		if len(parts) != 2 {
+			return nil
+		}
+		return &relation{
+			Table:        parts[0],
+			LocalColumn:  "id",
+			RemoteColumn: parts[1],
+			Multi:        f.Type.Kind() == reflect.Slice,
+			Nullable:     true,
+			Model:        structType(elem),
+		}
+	}
+
+	if through := getOptionValue(f.Tag, "m2m"); through != "" {
+		model := structType(elem)
+		if model == nil {
+			return nil
+		}
+		return &relation{
+			Table:        metaOf(model).Table,
+			LocalColumn:  "id",
+			RemoteColumn: "id",
+			Through:      through,
+			ThroughFrom:  getOptionValue(f.Tag, "from"),
+			ThroughTo:    getOptionValue(f.Tag, "to"),
+			Multi:        true,
+			Nullable:     true,
+			Model:        model,
+		}
+	}
+	return nil
+}
+
+func (f *fieldMeta) forwardRelation(target string) *relation {
+	parts := strings.Split(target, ".")
+	if len(parts) != 2 {
+		return nil
+	}
+
+	// The related type is only known if the field holds the model itself
+	// (e.g. a *Author populated by SelectRelated) or it is registered
+	model := structType(f.Type)
+	if model == nil || metaOf(model).Table != parts[0] {
+		model = nil
+		if registered, err := apps.Apps.GetModel(parts[0]); err == nil {
+			model = structType(reflect.TypeOf(registered))
+		}
+	}
+
+	return &relation{
+		Table:        parts[0],
+		LocalColumn:  f.Column,
+		RemoteColumn: parts[1],
+		Nullable:     hasOption(f.Tag, "null") || f.Type.Kind() == reflect.Ptr,
+		Model:        model,
+	}
+}
+
+// structType returns t's struct type if t is a struct or pointer to one
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t.String() == "time.Time" {
+		return nil
+	}
+	return t
+}
+
+func isTagOption(s string) bool {
+	switch s {
+	case "null", "unique", "primary_key", "index", "auto_increment", "blank",
+		"auto_now", "auto_now_add", "write_only", "read_only", "annotation":
+		return true
+	}
+	return false
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteString(strings.ToLower(string(r)))
+	}
+	return b.String()
+}
@@ -0,0 +1,446 @@
+package queryset
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// Prefetch customizes a relation loaded by PrefetchRelated:
+//
+//	authors.PrefetchRelated(Prefetch{
+//		Lookup:   "books",
+//		QuerySet: NewQuerySet[*Book](nil).Filter(Q{"pages__gt": 100}).OrderBy("-pages"),
+//		ToAttr:   "LongBooks",
+//	})
+type Prefetch struct {
+	// Lookup is the relation path, e.g. "books" or "books__tags"
+	Lookup string
+	// QuerySet filters and orders the rows of the last relation in Lookup.
+	// It must be a *QuerySet of the related model; nil loads every related row.
+	QuerySet RelatedQuerySet
+	// ToAttr names the field (by Go name) receiving the related rows instead
+	// of the relation field, keeping a filtered subset apart
+	ToAttr string
+}
+
+// RelatedQuerySet is implemented by every *QuerySet so that prefetches can
+// run querysets of a related model only known at runtime
+type RelatedQuerySet interface {
+	modelType() reflect.Type
+	fetchRelated(ctx context.Context, database *db.DB, filter Node) ([]reflect.Value, error)
+}
+
+func (q *QuerySet[T]) modelType() reflect.Type {
+	return q.meta().Type
+}
+
+// fetchRelated loads the rows matching q and filter as pointers to structs.
+// Querysets without a database of their own run on the parent's.
+func (q *QuerySet[T]) fetchRelated(ctx context.Context, database *db.DB, filter Node) ([]reflect.Value, error) {
+	qs := q.Filter(filter)
+	if qs.db == nil && qs.alias == "" {
+		qs.db = database
+	}
+	if qs.ctx == nil {
+		qs.ctx = ctx
+	}
+
+	results, err := qs.All()
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]reflect.Value, len(results))
+	for i := range results {
+		v := reflect.ValueOf(results[i])
+		if v.Kind() != reflect.Ptr {
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			v = ptr
+		}
+		objs[i] = v
+	}
+	return objs, nil
+}
+
+// prefetch loads the queryset's PrefetchRelated lookups into results
+func (q *QuerySet[T]) prefetch(results []T) error {
+	if len(q.prefetchRelated) == 0 || len(results) == 0 {
+		return nil
+	}
+
+	objs := make([]reflect.Value, len(results))
+	for i := range results {
+		v := reflect.ValueOf(&results[i]).Elem()
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		objs[i] = v
+	}
+
+	p := &prefetcher{ctx: q.getContext(), db: q.db, done: make(map[string][]int)}
+	for _, lookup := range q.prefetchRelated {
+		if err := p.run(objs, q.meta(), lookup); err != nil {
+			return fmt.Errorf("prefetch_related %q: %w", lookup.Lookup, err)
+		}
+	}
+	return nil
+}
+
+// prefetcher loads relations into model structs, one query per relation level
+type prefetcher struct {
+	ctx context.Context
+	db  *db.DB
+	// done maps prefetched paths to the index of the field they were loaded into
+	done map[string][]int
+}
+
+// run walks lookup's path, loading each level not loaded yet and the last
+// level with the lookup's queryset and target field
+func (p *prefetcher) run(objs []reflect.Value, meta *modelMeta, lookup Prefetch) error {
+	names := strings.Split(lookup.Lookup, "__")
+	path := ""
+	for i, name := range names {
+		last := i == len(names)-1
+		if path != "" {
+			path += "__"
+		}
+		path += name
+
+		f := meta.field(name)
+		if f == nil {
+			return fmt.Errorf("%s has no field %q", meta.Type.Name(), name)
+		}
+		rel := f.relation()
+		model := structType(elemType(f.Type))
+		if rel == nil || model == nil {
+			return fmt.Errorf("%q is not a relation holding related models", name)
+		}
+
+		index, done := p.done[path]
+		if !done || (last && (lookup.QuerySet != nil || lookup.ToAttr != "")) {
+			index = f.Index
+			if last && lookup.ToAttr != "" {
+				attr, ok := meta.Type.FieldByName(lookup.ToAttr)
+				if !ok {
+					return fmt.Errorf("%s has no field %q for to_attr", meta.Type.Name(), lookup.ToAttr)
+				}
+				index = attr.Index
+			}
+
+			var qs RelatedQuerySet
+			if last {
+				qs = lookup.QuerySet
+			}
+			if err := p.load(objs, f, rel, model, qs, index); err != nil {
+				return err
+			}
+			if !done {
+				p.done[path] = index
+			}
+		}
+
+		objs = relatedObjects(objs, index)
+		meta = metaOf(model)
+	}
+	return nil
+}
+
+// load fetches the related rows of rel for objs and stores them in the
+// field at index: appended for slices, assigned for forward relations
+func (p *prefetcher) load(objs []reflect.Value, f *fieldMeta, rel *relation, model reflect.Type, qs RelatedQuerySet, index []int) error {
+	switch {
+	case rel.Through != "":
+		return p.loadManyToMany(objs, rel, model, qs, index)
+	case rel.LocalColumn == "id":
+		// Reverse foreign key: related rows point at objs
+		byKey := groupByKey(objs, func(obj reflect.Value) interface{} { return primaryKey(obj) })
+		reset(objs, index)
+		if len(byKey) == 0 {
+			return nil
+		}
+		related, err := p.fetchIn(model, qs, rel.RemoteColumn, keysOf(byKey))
+		if err != nil {
+			return err
+		}
+		fk := metaOf(model).field(rel.RemoteColumn)
+		if fk == nil {
+			return fmt.Errorf("%s has no field %q", model.Name(), rel.RemoteColumn)
+		}
+		for _, r := range related {
+			key := keyOf(columnValue(r.Elem().FieldByIndex(fk.Index), fk.Tag))
+			for _, obj := range byKey[key].objs {
+				assignRelated(obj.FieldByIndex(index), r)
+			}
+		}
+	default:
+		// Forward foreign key: objs point at the related rows
+		byKey := groupByKey(objs, func(obj reflect.Value) interface{} {
+			return columnValue(obj.FieldByIndex(f.Index), f.Tag)
+		})
+		reset(objs, index)
+		if len(byKey) == 0 {
+			return nil
+		}
+		related, err := p.fetchIn(model, qs, rel.RemoteColumn, keysOf(byKey))
+		if err != nil {
+			return err
+		}
+		remote := metaOf(model).field(rel.RemoteColumn)
+		if remote == nil {
+			return fmt.Errorf("%s has no field %q", model.Name(), rel.RemoteColumn)
+		}
+		for _, r := range related {
+			key := keyOf(r.Elem().FieldByIndex(remote.Index).Interface())
+			for _, obj := range byKey[key].objs {
+				assignRelated(obj.FieldByIndex(index), r)
+			}
+		}
+	}
+	return nil
+}
+
+// loadManyToMany reads the through table, then the related rows in the
+// order of the related queryset
+func (p *prefetcher) loadManyToMany(objs []reflect.Value, rel *relation, model reflect.Type, qs RelatedQuerySet, index []int) error {
+	byKey := groupByKey(objs, func(obj reflect.Value) interface{} { return primaryKey(obj) })
+	reset(objs, index)
+	if len(byKey) == 0 {
+		return nil
+	}
+
+	// owners maps each related id to the keys of the objs linked to it
+	owners := make(map[string][]string)
+	var relatedIDs []interface{}
+	ids := keysOf(byKey)
+	for _, batch := range batches(len(ids), 1, prefetchBatch) {
+		c := newCompiler(p.db.Ops(), nil)
+		placeholders := make([]string, 0, batch[1]-batch[0])
+		for _, id := range ids[batch[0]:batch[1]] {
+			placeholders = append(placeholders, c.param(id))
+		}
+		query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)",
+			rel.ThroughFrom, rel.ThroughTo, rel.Through, rel.ThroughFrom, strings.Join(placeholders, ", "))
+		if err := p.readThrough(query, c.args, owners, &relatedIDs); err != nil {
+			return err
+		}
+	}
+	if len(relatedIDs) == 0 {
+		return nil
+	}
+
+	related, err := p.fetchIn(model, qs, rel.RemoteColumn, relatedIDs)
+	if err != nil {
+		return err
+	}
+	for _, r := range related {
+		for _, owner := range owners[keyOf(primaryKey(r.Elem()))] {
+			for _, obj := range byKey[owner].objs {
+				assignRelated(obj.FieldByIndex(index), r)
+			}
+		}
+	}
+	return nil
+}
+
+// readThrough reads the (from, to) pairs of a through table query into
+// owners, collecting the related ids in the order first seen
+func (p *prefetcher) readThrough(query string, args []interface{}, owners map[string][]string, relatedIDs *[]interface{}) error {
+	rows, err := p.db.QueryContext(p.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var from, to interface{}
+		if err := rows.Scan(&from, &to); err != nil {
+			return err
+		}
+		if _, seen := owners[keyOf(to)]; !seen {
+			*relatedIDs = append(*relatedIDs, normalize(to))
+		}
+		owners[keyOf(to)] = append(owners[keyOf(to)], keyOf(from))
+	}
+	return rows.Err()
+}
+
+// prefetchBatch is the most keys one prefetch query filters on, leaving
+// room under maxBulkParams for the arguments of a Prefetch queryset's filters
+const prefetchBatch = maxBulkParams / 2
+
+// fetchIn loads the rows of model whose column is in keys, through qs if
+// given, one query per batch of keys. A queryset's ordering holds within
+// each batch.
+func (p *prefetcher) fetchIn(model reflect.Type, qs RelatedQuerySet, column string, keys []interface{}) ([]reflect.Value, error) {
+	var related []reflect.Value
+	for _, batch := range batches(len(keys), 1, prefetchBatch) {
+		rows, err := p.fetch(model, qs, Q{column + "__in": keys[batch[0]:batch[1]]})
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, rows...)
+	}
+	return related, nil
+}
+
+// fetch loads rows of model matching filter, through qs if given
+func (p *prefetcher) fetch(model reflect.Type, qs RelatedQuerySet, filter Node) ([]reflect.Value, error) {
+	if qs == nil {
+		return fetchModel(p.ctx, p.db, metaOf(model), filter)
+	}
+	if qs.modelType() != model {
+		return nil, fmt.Errorf("a queryset of %s cannot load %s", qs.modelType().Name(), model.Name())
+	}
+	return qs.fetchRelated(p.ctx, p.db, filter)
+}
+
+// fetchModel loads every row of a model matching filter as pointers to structs
+func fetchModel(ctx context.Context, database *db.DB, meta *modelMeta, filter Node) ([]reflect.Value, error) {
+	c := newCompiler(database.Ops(), meta)
+	query := fmt.Sprintf("SELECT %s.* FROM %s", meta.Table, meta.Table)
+	where := strings.Join(c.conditions([]Node{filter}), " AND ")
+	if c.err != nil {
+		return nil, c.err
+	}
+	query += c.joinSQL()
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	rows, err := database.QueryContext(ctx, query, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := newRowScanner(meta.Type, cols)
+	var objs []reflect.Value
+	for rows.Next() {
+		obj := reflect.New(meta.Type)
+		if err := scanner.scan(rows, obj.Elem()); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, rows.Err()
+}
+
+// keyGroup collects the objs sharing a key, remembering the key's value
+type keyGroup struct {
+	value interface{}
+	objs  []reflect.Value
+}
+
+// groupByKey groups objs by key, skipping objs whose key is nil. Keys are
+// compared by their printed form, since drivers and structs may use
+// different integer types for the same id.
+func groupByKey(objs []reflect.Value, key func(reflect.Value) interface{}) map[string]*keyGroup {
+	groups := make(map[string]*keyGroup)
+	for _, obj := range objs {
+		v := key(obj)
+		if v == nil {
+			continue
+		}
+		k := keyOf(v)
+		if groups[k] == nil {
+			groups[k] = &keyGroup{value: v}
+		}
+		groups[k].objs = append(groups[k].objs, obj)
+	}
+	return groups
+}
+
+func keysOf(groups map[string]*keyGroup) []interface{} {
+	keys := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		keys = append(keys, g.value)
+	}
+	return keys
+}
+
+func keyOf(v interface{}) string {
+	return fmt.Sprint(normalize(v))
+}
+
+// primaryKey returns the id of a model struct
+func primaryKey(obj reflect.Value) interface{} {
+	f := metaOf(obj.Type()).field("id")
+	if f == nil {
+		return nil
+	}
+	return obj.FieldByIndex(f.Index).Interface()
+}
+
+// reset clears the field at index, so loading replaces anything already there
+func reset(objs []reflect.Value, index []int) {
+	for _, obj := range objs {
+		target := obj.FieldByIndex(index)
+		target.Set(reflect.Zero(target.Type()))
+	}
+}
+
+// assignRelated stores the related row r (a pointer to a struct) in target:
+// appended to slices of structs or pointers, or assigned to a single field
+func assignRelated(target reflect.Value, r reflect.Value) {
+	if target.Kind() == reflect.Slice {
+		item := r
+		if target.Type().Elem().Kind() != reflect.Ptr {
+			item = r.Elem()
+		}
+		target.Set(reflect.Append(target, item))
+		return
+	}
+	if target.Kind() == reflect.Ptr {
+		target.Set(r)
+		return
+	}
+	target.Set(r.Elem())
+}
+
+// relatedObjects returns the related structs loaded into the field at index,
+// so the next level of a nested lookup can be loaded into them. A struct
+// shared by several objs, such as the author of several books, is returned
+// once, so its relations aren't loaded into it once per owner.
+func relatedObjects(objs []reflect.Value, index []int) []reflect.Value {
+	var related []reflect.Value
+	seen := make(map[uintptr]bool)
+	add := func(v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() || seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+			v = v.Elem()
+		}
+		related = append(related, v)
+	}
+
+	for _, obj := range objs {
+		field := obj.FieldByIndex(index)
+		if field.Kind() == reflect.Slice {
+			for i := 0; i < field.Len(); i++ {
+				add(field.Index(i))
+			}
+			continue
+		}
+		add(field)
+	}
+	return related
+}
+
+// elemType unwraps slices and pointers
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
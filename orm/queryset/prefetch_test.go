@@ -0,0 +1,157 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+	"github.com/anuragcarret/djang-drf-go/orm/db/dbtest"
+)
+
+type liteTag struct {
+	ID   uint64 `drf:"id;primary_key;auto_increment"`
+	Name string `drf:"name"`
+}
+
+func (t *liteTag) TableName() string { return "lite_tags" }
+
+// liteShelfBook is lite_books with its tags
+type liteShelfBook struct {
+	ID       uint64    `drf:"id;primary_key;auto_increment"`
+	Title    string    `drf:"title;max_length=100"`
+	Pages    int64     `drf:"pages"`
+	AuthorID uint64    `drf:"author_id;foreign_key=lite_authors.id"`
+	Tags     []liteTag `drf:"m2m=lite_book_tags;to=tag_id;from=book_id"`
+}
+
+func (b *liteShelfBook) TableName() string { return "lite_books" }
+
+// liteShelfAuthor is lite_authors with its books as pointers
+type liteShelfAuthor struct {
+	ID        uint64           `drf:"id;primary_key;auto_increment"`
+	Name      string           `drf:"name;max_length=100"`
+	Books     []*liteShelfBook `drf:"relation=lite_books.author_id"`
+	LongBooks []*liteShelfBook `drf:"-"`
+}
+
+func (a *liteShelfAuthor) TableName() string { return "lite_authors" }
+
+// liteShelfNovel is lite_books with its author and the author's books
+type liteShelfNovel struct {
+	ID     uint64           `drf:"id;primary_key;auto_increment"`
+	Title  string           `drf:"title;max_length=100"`
+	Author *liteShelfAuthor `drf:"author_id;foreign_key=lite_authors.id"`
+}
+
+func (n *liteShelfNovel) TableName() string { return "lite_books" }
+
+// newShelfDB extends the library with tags: Go is tagged "lang", SQL "db" and "lang"
+func newShelfDB(t *testing.T) *db.DB {
+	t.Helper()
+	database := newLibraryDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE lite_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)",
+		"CREATE TABLE lite_book_tags (book_id BIGINT NOT NULL, tag_id BIGINT NOT NULL)",
+		"INSERT INTO lite_tags (name) VALUES ('lang'), ('db')",
+		"INSERT INTO lite_book_tags (book_id, tag_id) VALUES (1, 1), (2, 2), (2, 1)",
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return database
+}
+
+func TestPrefetch(t *testing.T) {
+	database := newShelfDB(t)
+	authors := NewQuerySet[*liteShelfAuthor](database).OrderBy("name")
+
+	var results []*liteShelfAuthor
+	dbtest.AssertNumQueries(t, 4, func() {
+		var err error
+		results, err = authors.PrefetchRelated("books__tags").All()
+		if err != nil {
+			t.Fatalf("PrefetchRelated failed: %v", err)
+		}
+	})
+
+	describe := func(a *liteShelfAuthor, books []*liteShelfBook) string {
+		var parts []string
+		for _, b := range books {
+			var tags []string
+			for _, tag := range b.Tags {
+				tags = append(tags, tag.Name)
+			}
+			parts = append(parts, b.Title+"["+strings.Join(tags, ",")+"]")
+		}
+		return a.Name + ":" + strings.Join(parts, " ")
+	}
+	var got []string
+	for _, a := range results {
+		got = append(got, describe(a, a.Books))
+	}
+	if strings.Join(got, "; ") != "Ann:Go[lang] SQL[lang,db]; Bob:Rust[]; Cid:" {
+		t.Errorf("unexpected prefetch: %v", got)
+	}
+
+	long := Prefetch{
+		Lookup:   "books",
+		QuerySet: NewQuerySet[*liteShelfBook](nil).Filter(Q{"pages__gte": 200}).OrderBy("-pages"),
+		ToAttr:   "LongBooks",
+	}
+	results, err := authors.PrefetchRelated(long).All()
+	if err != nil {
+		t.Fatalf("Prefetch with queryset failed: %v", err)
+	}
+	got = nil
+	for _, a := range results {
+		got = append(got, describe(a, a.LongBooks)+fmt.Sprintf("(%d)", len(a.Books)))
+	}
+	if strings.Join(got, "; ") != "Ann:SQL[](0); Bob:Rust[](0); Cid:(0)" {
+		t.Errorf("unexpected to_attr prefetch: %v", got)
+	}
+
+	books, err := NewQuerySet[*liteNovel](database).PrefetchRelated("author__profile").OrderBy("id").All()
+	if err != nil {
+		t.Fatalf("forward prefetch failed: %v", err)
+	}
+	if a := books[0].Author; a == nil || a.Name != "Ann" || a.Profile == nil || a.Profile.Bio != "Writes about Go" {
+		t.Errorf("unexpected forward prefetch: %+v", a)
+	}
+
+	// Ann's two novels share her struct, whose books must be loaded once
+	novels, err := NewQuerySet[*liteShelfNovel](database).PrefetchRelated("author__books").OrderBy("id").All()
+	if err != nil {
+		t.Fatalf("nested forward prefetch failed: %v", err)
+	}
+	if novels[0].Author != novels[1].Author || len(novels[0].Author.Books) != 2 || len(novels[2].Author.Books) != 1 {
+		t.Errorf("expected Ann's 2 books and Bob's 1, got %d and %d", len(novels[0].Author.Books), len(novels[2].Author.Books))
+	}
+
+	wrong := Prefetch{Lookup: "books", QuerySet: NewQuerySet[*liteTag](nil)}
+	if _, err := authors.PrefetchRelated(wrong).All(); err == nil {
+		t.Error("expected an error for a queryset of the wrong model")
+	}
+}
+
+func TestPrefetchBatches(t *testing.T) {
+	database := newShelfDB(t)
+	// More authors than SQLite accepts bind parameters in one statement
+	if _, err := database.Exec(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 40000)
+		INSERT INTO lite_authors (name) SELECT 'author ' || i FROM n`); err != nil {
+		t.Fatalf("insert authors failed: %v", err)
+	}
+
+	var results []*liteShelfAuthor
+	dbtest.AssertNumQueries(t, 1+3+2, func() {
+		var err error
+		results, err = NewQuerySet[*liteShelfAuthor](database).PrefetchRelated("books__tags").All()
+		if err != nil {
+			t.Fatalf("PrefetchRelated failed: %v", err)
+		}
+	})
+	if len(results) != 40003 || len(results[0].Books) != 2 || len(results[0].Books[1].Tags) != 2 {
+		t.Errorf("expected Ann's books and tags to be prefetched, got %d authors", len(results))
+	}
+}
@@ -0,0 +1,297 @@
+package queryset
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Node is a filter condition accepted by Filter and Exclude: a Q of
+// lookups, an Exists subquery, or a composition of nodes built with And, Or
+// and Not
+type Node interface {
+	node()
+}
+
+// Q represents query parameters for filtering. All lookups in a Q must match.
+type Q map[string]interface{}
+
+func (Q) node() {}
+
+// Connectors for combined nodes
+const (
+	AND = "AND"
+	OR  = "OR"
+)
+
+// Combined joins child nodes with a connector, optionally negated
+type Combined struct {
+	Connector string
+	Children  []Node
+	Negated   bool
+}
+
+func (*Combined) node() {}
+
+// And matches when every node matches
+func And(nodes ...Node) Node {
+	return &Combined{Connector: AND, Children: nodes}
+}
+
+// Or matches when any node matches
+func Or(nodes ...Node) Node {
+	return &Combined{Connector: OR, Children: nodes}
+}
+
+// Not negates a node
+func Not(n Node) Node {
+	return &Combined{Connector: AND, Children: []Node{n}, Negated: true}
+}
+
+// conditions renders top-level nodes as a list of conditions to be ANDed,
+// splitting each Q into its individual lookups
+func (c *compiler) conditions(nodes []Node) []string {
+	var conds []string
+	for _, n := range nodes {
+		if q, ok := n.(Q); ok {
+			for _, k := range sortedLookups(q) {
+				conds = append(conds, c.lookup(k, q[k]))
+			}
+			continue
+		}
+		if sql := c.compile(n); sql != "" {
+			conds = append(conds, sql)
+		}
+	}
+	return conds
+}
+
+// compile renders n, returning "" for a node with no conditions
+func (c *compiler) compile(n Node) string {
+	switch n := n.(type) {
+	case nil:
+		return ""
+	case Q:
+		parts := make([]string, 0, len(n))
+		for _, k := range sortedLookups(n) {
+			parts = append(parts, c.lookup(k, n[k]))
+		}
+		return c.connect(AND, parts)
+	case *Combined:
+		if n.Negated {
+			c.negated++
+			defer func() { c.negated-- }()
+		}
+		parts := make([]string, 0, len(n.Children))
+		for _, child := range n.Children {
+			if sql := c.compile(child); sql != "" {
+				parts = append(parts, sql)
+			}
+		}
+		sql := c.connect(n.Connector, parts)
+		if sql != "" && n.Negated {
+			sql = "NOT " + parenthesize(sql)
+		}
+		return sql
+	case *ExistsExpression:
+		return n.sql(c)
+	}
+	panic(fmt.Sprintf("queryset: unsupported filter node %T", n))
+}
+
+// sortedLookups returns the keys of q in order, so its SQL is stable
+func sortedLookups(q Q) []string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *compiler) connect(connector string, parts []string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+	if connector != OR {
+		connector = AND
+	}
+	return "(" + strings.Join(parts, " "+connector+" ") + ")"
+}
+
+func parenthesize(sql string) string {
+	if strings.HasPrefix(sql, "(") && strings.HasSuffix(sql, ")") {
+		return sql
+	}
+	return "(" + sql + ")"
+}
+
+func (c *compiler) lookup(key string, v interface{}) string {
+	path, transforms, operator, err := c.parseLookup(key)
+	if err != nil {
+		c.fail(err)
+		return ""
+	}
+	if c.negated > 0 && c.multiValued(path) {
+		// Excluding "books__title" must exclude rows with any such related
+		// row, not just the joined rows that match
+		return c.subselect(func(sub *compiler) string { return sub.lookup(key, v) })
+	}
+	sql := c.condition(key, path, transforms, operator, v)
+	if sql != "" && c.negated > 0 && operator != "isnull" && v != nil && c.nullable(path) {
+		// A comparison with NULL isn't true, so NOT of it isn't either.
+		// As in Django, excluding a value keeps the rows where it is NULL.
+		sql = fmt.Sprintf("(%s AND %s IS NOT NULL)", sql, c.ref(path))
+	}
+	return sql
+}
+
+// condition renders the lookup operator applied to the field path
+func (c *compiler) condition(key, path string, transforms []string, operator string, v interface{}) string {
+	column := c.ref(path)
+	for _, name := range transforms {
+		column = c.transform(name)(c.d, column)
+	}
+
+	if sq, ok := v.(Subqueryable); ok {
+		if operator == "in" {
+			return fmt.Sprintf("%s IN %s", column, sq.subquery(c))
+		}
+		if _, isExpr := v.(Expression); !isExpr {
+			v = Subquery(sq)
+		}
+	}
+	_, isExpr := v.(Expression)
+
+	if l, ok := registeredLookup(operator); ok {
+		if l.Prepare != nil && !isExpr {
+			v = l.Prepare(v)
+		}
+		return l.SQL(c.d, column, c.value(v))
+	}
+
+	switch operator {
+	case "in":
+		vals := reflect.ValueOf(v)
+		if vals.Kind() != reflect.Slice && vals.Kind() != reflect.Array {
+			c.fail(fmt.Errorf("lookup %q needs a slice, got %T", key, v))
+			return ""
+		}
+		if vals.Len() == 0 {
+			// Nothing can be IN an empty list
+			return "1 = 0"
+		}
+		placeholders := []string{}
+		for idx := 0; idx < vals.Len(); idx++ {
+			placeholders = append(placeholders, c.value(vals.Index(idx).Interface()))
+		}
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
+	case "isnull":
+		isNull, ok := v.(bool)
+		if !ok {
+			c.fail(fmt.Errorf("lookup %q needs a bool, got %T", key, v))
+			return ""
+		}
+		if isNull {
+			return column + " IS NULL"
+		}
+		return column + " IS NOT NULL"
+	case "range":
+		bounds := reflect.ValueOf(v)
+		if (bounds.Kind() != reflect.Slice && bounds.Kind() != reflect.Array) || bounds.Len() != 2 {
+			c.fail(fmt.Errorf("lookup %q needs a slice of two bounds, got %v", key, v))
+			return ""
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, c.value(bounds.Index(0).Interface()), c.value(bounds.Index(1).Interface()))
+	case "exact":
+		if v == nil {
+			return column + " IS NULL"
+		}
+	}
+
+	op, ok := c.d.Operator(operator)
+	if !ok {
+		c.fail(fmt.Errorf("the %s backend does not support the %q lookup", c.d.Name(), operator))
+		return ""
+	}
+	if !isExpr {
+		v = likePattern(operator, v)
+	}
+	return fmt.Sprintf(op, column, c.value(v))
+}
+
+// likeEscaper escapes the LIKE wildcards, and the escape character itself,
+// in a lookup value. The dialects' LIKE operators declare ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern escapes v and adds the wildcards of LIKE-based lookups, so
+// that the value itself is matched literally
+func likePattern(operator string, v interface{}) interface{} {
+	switch operator {
+	case "iexact":
+		return likeEscaper.Replace(fmt.Sprint(v))
+	case "contains", "icontains":
+		return "%" + likeEscaper.Replace(fmt.Sprint(v)) + "%"
+	case "startswith", "istartswith":
+		return likeEscaper.Replace(fmt.Sprint(v)) + "%"
+	case "endswith", "iendswith":
+		return "%" + likeEscaper.Replace(fmt.Sprint(v))
+	}
+	return v
+}
+
+// parseLookup splits a lookup such as "author__created_at__year__gte" into
+// the field path, the transforms applied to it and the lookup, which
+// defaults to "exact". Fields are matched first, as in Django, so a field
+// named like a transform still refers to the field.
+func (c *compiler) parseLookup(key string) (string, []string, string, error) {
+	parts := strings.Split(key, "__")
+
+	n, meta, plain := 0, c.base, ""
+	for n < len(parts) {
+		var f *fieldMeta
+		if meta != nil {
+			f = meta.field(parts[n])
+		}
+		if f == nil {
+			// Not a declared field (or the related model is unknown): trust
+			// that it is a column unless it names a transform or lookup
+			if !c.isTransform(parts[n]) && !c.isLookup(parts[n]) {
+				n++
+			}
+			break
+		}
+		n++
+		rel := f.relation()
+		if rel == nil {
+			plain = f.Column
+			break
+		}
+		meta = nil
+		if rel.Model != nil {
+			meta = metaOf(rel.Model)
+		}
+	}
+	if n == 0 {
+		return "", nil, "", fmt.Errorf("cannot resolve %q into a field", key)
+	}
+
+	rest, operator := parts[n:], "exact"
+	if len(rest) > 0 && c.isLookup(rest[len(rest)-1]) {
+		operator, rest = rest[len(rest)-1], rest[:len(rest)-1]
+	}
+	for i, name := range rest {
+		if c.isTransform(name) {
+			continue
+		}
+		if i == 0 && plain != "" {
+			return "", nil, "", fmt.Errorf("unsupported lookup %q in %q: field %q is not a relation", name, key, plain)
+		}
+		return "", nil, "", fmt.Errorf("unsupported lookup %q in %q", name, key)
+	}
+	return strings.Join(parts[:n], "__"), rest, operator, nil
+}
@@ -1,18 +1,26 @@
 package queryset
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/anuragcarret/djang-drf-go/core/apps"
+	"github.com/anuragcarret/djang-drf-go/orm/connections"
 	"github.com/anuragcarret/djang-drf-go/orm/db"
 )
 
-// Q represents query parameters for filtering
-type Q map[string]interface{}
+// Errors returned by terminal methods expecting exactly one object, for use
+// with errors.Is
+var (
+	// ErrDoesNotExist means no row matched
+	ErrDoesNotExist = errors.New("object does not exist")
+	// ErrMultipleObjectsReturned means Get matched more than one row
+	ErrMultipleObjectsReturned = errors.New("multiple objects returned")
+)
 
 // ModelInterface mirrors orm.ModelInterface to avoid circular dependency
 type ModelInterface interface {
@@ -22,40 +30,125 @@ type ModelInterface interface {
 // QuerySet is a lazy, chainable query builder
 type QuerySet[T ModelInterface] struct {
 	db              *db.DB
-	filters         []Q
-	excludes        []Q
+	alias           string
+	ctx             context.Context
+	filters         []Node
+	excludes        []Node
 	ordering        []string
 	limit           int
 	offset          int
 	distinct        bool
-	forUpdate       bool
+	forUpdate       *ForUpdate
 	selectRelated   []string
-	prefetchRelated []string
+	prefetchRelated []Prefetch
+	annotations     []annotation
+	only            []string
+	deferred        []string
+	combined        *combination[T]
 }
 
-// NewQuerySet creates a new queryset
+// NewQuerySet creates a new queryset.
+// With a nil database, each query is routed to a connection chosen by the
+// routers installed in the connections package.
 func NewQuerySet[T ModelInterface](database *db.DB) *QuerySet[T] {
 	return &QuerySet[T]{
 		db:              database,
-		filters:         make([]Q, 0),
-		excludes:        make([]Q, 0),
+		filters:         make([]Node, 0),
+		excludes:        make([]Node, 0),
 		ordering:        make([]string, 0),
 		selectRelated:   make([]string, 0),
-		prefetchRelated: make([]string, 0),
+		prefetchRelated: make([]Prefetch, 0),
 	}
 }
 
-// SelectRelated specifies foreign-key relationships to follow in the query
+// WithContext sets the context used by terminal operations for cancellation
+// and deadlines
+func (q *QuerySet[T]) WithContext(ctx context.Context) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.ctx = ctx
+	return newQs
+}
+
+// getContext returns the queryset's context, defaulting to context.Background
+func (q *QuerySet[T]) getContext() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+// WithTx binds the queryset to a transaction-bound DB obtained from db.Atomic
+func (q *QuerySet[T]) WithTx(tx *db.DB) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.db = tx
+	newQs.alias = ""
+	return newQs
+}
+
+// Using runs the queryset on the named connection, bypassing routers
+func (q *QuerySet[T]) Using(alias string) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.db = nil
+	newQs.alias = alias
+	return newQs
+}
+
+// resolve returns a queryset bound to the database it should run on.
+// Explicit databases win; otherwise Using's alias or the routers decide.
+func (q *QuerySet[T]) resolve(write bool) (*QuerySet[T], error) {
+	if q.db != nil && q.alias == "" {
+		return q, nil
+	}
+	// Rows are locked on the database they will be written to
+	write = write || q.forUpdate != nil
+
+	alias := q.alias
+	if alias == "" {
+		var zero T
+		if write {
+			alias = connections.Connections.DBForWrite(q.getContext(), zero)
+		} else {
+			alias = connections.Connections.DBForRead(q.getContext(), zero)
+		}
+	}
+
+	conn, err := connections.Get(alias)
+	if err != nil {
+		return nil, err
+	}
+	newQs := q.clone()
+	newQs.db = conn
+	newQs.alias = ""
+	return newQs, nil
+}
+
+// SelectRelated loads foreign-key and one-to-one relations in the same query.
+// Fields holding the related model (e.g. Author *Author tagged
+// foreign_key=authors.id) are populated; paths like "author__profile"
+// follow several levels.
 func (q *QuerySet[T]) SelectRelated(fields ...string) *QuerySet[T] {
 	newQs := q.clone()
 	newQs.selectRelated = append(newQs.selectRelated, fields...)
 	return newQs
 }
 
-// PrefetchRelated specifies Many-to-Many or reverse relationships to fetch in separate queries
-func (q *QuerySet[T]) PrefetchRelated(fields ...string) *QuerySet[T] {
+// PrefetchRelated loads relations in one extra query per relation and
+// level. Lookups are relation paths such as "books" or "books__tags", or
+// Prefetch values that customize the related queryset and target field.
+func (q *QuerySet[T]) PrefetchRelated(lookups ...interface{}) *QuerySet[T] {
 	newQs := q.clone()
-	newQs.prefetchRelated = append(newQs.prefetchRelated, fields...)
+	for _, lookup := range lookups {
+		switch l := lookup.(type) {
+		case string:
+			newQs.prefetchRelated = append(newQs.prefetchRelated, Prefetch{Lookup: l})
+		case Prefetch:
+			newQs.prefetchRelated = append(newQs.prefetchRelated, l)
+		case *Prefetch:
+			newQs.prefetchRelated = append(newQs.prefetchRelated, *l)
+		default:
+			panic(fmt.Sprintf("queryset: unsupported prefetch lookup %T", lookup))
+		}
+	}
 	return newQs
 }
 
@@ -63,45 +156,34 @@ func (q *QuerySet[T]) PrefetchRelated(fields ...string) *QuerySet[T] {
 func (q *QuerySet[T]) clone() *QuerySet[T] {
 	newQs := *q
 	// Copy slices to avoid shared state
-	newQs.filters = append([]Q(nil), q.filters...)
-	newQs.excludes = append([]Q(nil), q.excludes...)
+	newQs.filters = append([]Node(nil), q.filters...)
+	newQs.excludes = append([]Node(nil), q.excludes...)
 	newQs.ordering = append([]string(nil), q.ordering...)
 	newQs.selectRelated = append([]string(nil), q.selectRelated...)
-	newQs.prefetchRelated = append([]string(nil), q.prefetchRelated...)
+	newQs.prefetchRelated = append([]Prefetch(nil), q.prefetchRelated...)
+	newQs.annotations = append([]annotation(nil), q.annotations...)
+	newQs.only = append([]string(nil), q.only...)
+	newQs.deferred = append([]string(nil), q.deferred...)
 	return &newQs
 }
 
-// Filter adds positive filter criteria
-func (q *QuerySet[T]) Filter(params ...Q) *QuerySet[T] {
+// Filter adds positive filter criteria. Nodes are ANDed together, and
+// with the criteria of earlier Filter calls.
+func (q *QuerySet[T]) Filter(nodes ...Node) *QuerySet[T] {
 	newQs := q.clone()
-	if len(params) == 0 {
-		return newQs
-	}
-
-	merged := Q{}
-	for _, p := range params {
-		for k, v := range p {
-			merged[k] = v
-		}
-	}
-	newQs.filters = append(newQs.filters, merged)
+	newQs.filters = append(newQs.filters, nodes...)
 	return newQs
 }
 
-// Exclude adds negative filter criteria
-func (q *QuerySet[T]) Exclude(params ...Q) *QuerySet[T] {
+// Exclude adds negative filter criteria: rows matching all the given nodes
+// are excluded. Separate Exclude calls exclude independently. As in Django,
+// rows where an excluded nullable field is NULL don't match, so they stay.
+func (q *QuerySet[T]) Exclude(nodes ...Node) *QuerySet[T] {
 	newQs := q.clone()
-	if len(params) == 0 {
+	if len(nodes) == 0 {
 		return newQs
 	}
-
-	merged := Q{}
-	for _, p := range params {
-		for k, v := range p {
-			merged[k] = v
-		}
-	}
-	newQs.excludes = append(newQs.excludes, merged)
+	newQs.excludes = append(newQs.excludes, Not(And(nodes...)))
 	return newQs
 }
 
@@ -112,6 +194,24 @@ func (q *QuerySet[T]) OrderBy(fields ...string) *QuerySet[T] {
 	return newQs
 }
 
+// Only loads just the given fields (and the primary key) into results,
+// leaving the other fields at their zero values. It replaces earlier
+// Only and Defer calls.
+func (q *QuerySet[T]) Only(fields ...string) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.only = fields
+	newQs.deferred = nil
+	return newQs
+}
+
+// Defer skips loading the given fields, e.g. large text columns that a list
+// doesn't show. Deferred fields are left at their zero values.
+func (q *QuerySet[T]) Defer(fields ...string) *QuerySet[T] {
+	newQs := q.clone()
+	newQs.deferred = append(newQs.deferred, fields...)
+	return newQs
+}
+
 // Limit sets the maximum number of records
 func (q *QuerySet[T]) Limit(n int) *QuerySet[T] {
 	newQs := q.clone()
@@ -126,378 +226,401 @@ func (q *QuerySet[T]) Offset(n int) *QuerySet[T] {
 	return newQs
 }
 
-// SQL returns the generated SQL query and arguments
-func (q *QuerySet[T]) SQL() (string, []interface{}) {
-	tableName := q.getTableName()
+// SQL returns the generated SQL query and arguments, in the dialect of the
+// database the queryset would run on. It doesn't connect to the database.
+func (q *QuerySet[T]) SQL() (string, []interface{}, error) {
+	return q.render(nil)
+}
 
-	// Use table prefix to avoid ambiguity during joins
-	query := fmt.Sprintf("SELECT %s.* FROM %s", tableName, tableName)
-	args := []interface{}{}
+// render compiles q (with projection p) for SQL, without resolving its
+// database
+func (q *QuerySet[T]) render(p *projection) (string, []interface{}, error) {
+	d, err := q.dialect()
+	if err != nil {
+		return "", nil, err
+	}
+	c := newCompiler(d, q.meta())
+	query, err := q.compileWith(c, p)
+	return query, c.args, err
+}
 
-	// Handle SELECT RELATED (JOINs)
-	if len(q.selectRelated) > 0 {
+// dialect returns the dialect of the database q would run on, without
+// connecting: that of its bound database, or else of the configured alias
+// it is routed to. Querysets on no database at all use db.DefaultDialect.
+func (q *QuerySet[T]) dialect() (db.Dialect, error) {
+	if q.db != nil && q.alias == "" {
+		return q.db.Ops(), nil
+	}
+	alias := q.alias
+	if alias == "" {
+		// Replicas share the primary's engine, so read routing is as good
+		// as write routing here, and doesn't pin the context
 		var zero T
-		t := reflect.TypeOf(zero)
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-
-		for _, relField := range q.selectRelated {
-			// Find field by name (case-insensitive)
-			var field reflect.StructField
-			found := false
-			for i := 0; i < t.NumField(); i++ {
-				f := t.Field(i)
-				if strings.EqualFold(f.Name, relField) {
-					field = f
-					found = true
-					break
-				}
-			}
+		alias = connections.Connections.DBForRead(q.getContext(), zero)
+	}
+	d, err := connections.Dialect(alias)
+	if errors.Is(err, connections.ErrConnectionNotFound) && q.alias == "" && alias == connections.DefaultAlias {
+		return db.DefaultDialect, nil
+	}
+	return d, err
+}
 
-			if found {
-				tag := field.Tag.Get("drf")
-				parts := strings.Split(tag, ";")
-				localCol := parts[0]
-				var fk string
-				for _, p := range parts {
-					if strings.HasPrefix(p, "foreign_key=") {
-						fk = strings.TrimPrefix(p, "foreign_key=")
-						break
-					}
-				}
+// projection overrides what a compiled SELECT returns
+type projection struct {
+	// values selects fields (whole rows if empty) instead of model columns,
+	// grouping by them when an annotation aggregates
+	values bool
+	fields []string
+	// aggregate selects only these expressions, computed over all matching rows
+	aggregate []annotation
+}
 
-				if fk != "" {
-					fkParts := strings.Split(fk, ".")
-					if len(fkParts) == 2 {
-						relatedTable := fkParts[0]
-						relatedCol := fkParts[1]
-						query += fmt.Sprintf(" INNER JOIN %s ON %s.%s = %s.%s",
-							relatedTable, tableName, localCol, relatedTable, relatedCol)
-					}
-				}
-			}
+// compile renders the SELECT statement for q, or for the projection p
+func (q *QuerySet[T]) compile(p *projection) (string, []interface{}, error) {
+	c := newCompiler(q.db.Ops(), q.meta())
+	query, err := q.compileWith(c, p)
+	return query, c.args, err
+}
+
+// compileWith renders the SELECT statement using c, which may be nested in
+// an enclosing statement
+func (q *QuerySet[T]) compileWith(c *compiler, p *projection) (string, error) {
+	if q.combined != nil {
+		return q.compileCombined(c, p)
+	}
+
+	// select_related only applies when loading model rows
+	var related, relatedKeys []string
+	if p == nil {
+		var err error
+		if related, relatedKeys, err = q.selectRelatedColumns(c); err != nil {
+			return "", err
 		}
 	}
 
-	if len(q.filters) > 0 {
-		query += " WHERE "
-		for i, filter := range q.filters {
-			if i > 0 {
-				query += " AND "
-			}
-			j := 0
-			for k, v := range filter {
-				if j > 0 {
-					query += " AND "
-				}
+	grouped := false
+	for _, a := range q.annotations {
+		grouped = grouped || containsAggregate(a.expr)
+	}
 
-				column, operator := q.parseLookup(k)
-
-				switch operator {
-				case "in":
-					vals := reflect.ValueOf(v)
-					placeholders := []string{}
-					for idx := 0; idx < vals.Len(); idx++ {
-						args = append(args, vals.Index(idx).Interface())
-						placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
-					}
-					query += fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
-				case "contains":
-					query += fmt.Sprintf("%s LIKE $%d", column, len(args)+1)
-					args = append(args, fmt.Sprintf("%%%v%%", v))
-				case "icontains":
-					query += fmt.Sprintf("%s ILIKE $%d", column, len(args)+1)
-					args = append(args, fmt.Sprintf("%%%v%%", v))
-				case "iexact":
-					query += fmt.Sprintf("%s ILIKE $%d", column, len(args)+1)
-					args = append(args, v)
-				case "gt":
-					query += fmt.Sprintf("%s > $%d", column, len(args)+1)
-					args = append(args, v)
-				case "gte":
-					query += fmt.Sprintf("%s >= $%d", column, len(args)+1)
-					args = append(args, v)
-				case "lt":
-					query += fmt.Sprintf("%s < $%d", column, len(args)+1)
-					args = append(args, v)
-				case "lte":
-					query += fmt.Sprintf("%s <= $%d", column, len(args)+1)
-					args = append(args, v)
-				default:
-					query += fmt.Sprintf("%s = $%d", column, len(args)+1)
-					args = append(args, v)
-				}
-				j++
-			}
+	var columns, groupBy []string
+	switch {
+	case p != nil && p.aggregate != nil:
+		for _, a := range p.aggregate {
+			columns = append(columns, a.expr.sql(c)+" AS "+c.d.QuoteIdent(a.name))
 		}
-	}
-	// ... ordering/limit/offset omitted for brevity if using replace_file_content properly ...
-	if len(q.ordering) > 0 {
-		query += " ORDER BY "
-		for i, field := range q.ordering {
-			if i > 0 {
-				query += ", "
-			}
-			if field[0] == '-' {
-				query += fmt.Sprintf("%s DESC", field[1:])
-			} else {
-				query += field
+		grouped = false
+	case p != nil && p.values && len(p.fields) > 0:
+		for _, field := range p.fields {
+			if q.annotation(field) != nil {
+				continue
 			}
+			col := c.column(field, true)
+			columns = append(columns, col+" AS "+c.d.QuoteIdent(field))
+			groupBy = append(groupBy, col)
+		}
+	case (p == nil || p.values) && (len(q.only) > 0 || len(q.deferred) > 0):
+		for _, f := range q.loadedColumns(c) {
+			columns = append(columns, c.col(f.Column))
 		}
+		columns = append(columns, related...)
+		groupBy = append(append(groupBy, c.col("id")), relatedKeys...)
+	default:
+		columns = append(append(columns, c.alias+".*"), related...)
+		groupBy = append(append(groupBy, c.col("id")), relatedKeys...)
 	}
 
-	if q.limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", q.limit)
+	if p == nil || p.aggregate == nil {
+		for i, a := range q.annotations {
+			c.annotations = q.annotations[:i]
+			columns = append(columns, a.expr.sql(c)+" AS "+c.d.QuoteIdent(a.name))
+		}
+		// An annotation referring to an aggregate one aggregates too
+		grouped = grouped || c.aggregateRef
 	}
-	if q.offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", q.offset)
+	c.annotations = q.annotations
+
+	whereNodes, havingNodes, windowNodes := q.filterClauses(c)
+	var where string
+	if p != nil && p.aggregate != nil && q.filtersRepeatRows(c) {
+		// Aggregate each matching row once, however many related rows matched
+		where = c.subselect(func(sub *compiler) string { return strings.Join(sub.conditions(whereNodes), " AND ") })
+	} else {
+		where = strings.Join(c.conditions(whereNodes), " AND ")
 	}
-
-	return query, args
-}
-
-func (q *QuerySet[T]) parseLookup(key string) (string, string) {
-	parts := strings.Split(key, "__")
-	if len(parts) > 1 {
-		return parts[0], parts[1]
+	having := strings.Join(c.conditions(havingNodes), " AND ")
+	if having != "" && !grouped {
+		c.fail(fmt.Errorf("cannot filter on aggregate annotations while aggregating"))
+	}
+	if len(windowNodes) > 0 && p != nil && p.aggregate != nil {
+		c.fail(fmt.Errorf("cannot aggregate over a filter on window annotations"))
 	}
-	return key, "exact"
-}
 
-func (q *QuerySet[T]) getTableName() string {
-	var zero T
-	t := reflect.TypeOf(zero)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+	var order, lock string
+	if (p == nil || p.aggregate == nil) && len(windowNodes) == 0 {
+		order = q.orderBy(c)
+		lock = q.lockClause(c)
 	}
-	instance := reflect.New(t).Interface()
-	if m, ok := instance.(ModelInterface); ok {
-		return m.TableName()
+	if q.forUpdate != nil && len(windowNodes) > 0 {
+		c.fail(fmt.Errorf("select for update cannot be used with filters on window annotations"))
 	}
-	return ""
-}
 
-// All returns all matching records (Terminal operation)
-func (q *QuerySet[T]) All() ([]T, error) {
-	query, args := q.SQL()
-	rows, err := q.db.Query(query, args...)
-	if err != nil {
-		return nil, err
+	if c.err != nil {
+		return "", c.err
 	}
-	defer rows.Close()
 
-	cols, err := rows.Columns()
-	if err != nil {
-		return nil, err
+	query := "SELECT "
+	if q.distinct || (p == nil && !grouped && c.repeatsRows()) {
+		query += "DISTINCT "
+	}
+	query += strings.Join(columns, ", ") + " FROM " + aliased(c.base.Table, c.alias) + c.joinSQL()
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if grouped && len(groupBy) > 0 {
+		query += " GROUP BY " + strings.Join(groupBy, ", ")
+	}
+	if having != "" {
+		query += " HAVING " + having
 	}
 
-	var results []T
-	for rows.Next() {
-		var item T
-		val := reflect.ValueOf(&item).Elem()
-		if val.Kind() == reflect.Ptr {
-			val.Set(reflect.New(val.Type().Elem()))
+	if len(windowNodes) > 0 {
+		// Window functions are computed after WHERE and HAVING, so filters
+		// on them apply to the rows of the query computing them
+		c.alias, c.annotationAliases = c.tableAlias("windowed"), true
+		joins := len(c.joins)
+		conds := strings.Join(c.conditions(windowNodes), " AND ")
+		order = q.orderBy(c)
+		if len(c.joins) > joins {
+			c.fail(fmt.Errorf("cannot follow relations when filtering or ordering on window annotations"))
 		}
-
-		elem := val
-		if elem.Kind() == reflect.Ptr {
-			elem = elem.Elem()
+		if c.err != nil {
+			return "", c.err
 		}
+		query = fmt.Sprintf("SELECT * FROM (%s) AS %s WHERE %s", query, c.alias, conds)
+	}
 
-		scanDest := make([]interface{}, len(cols))
-		for i := range cols {
-			scanDest[i] = new(interface{})
-		}
+	if order != "" {
+		query += " ORDER BY " + order
+	}
+	if p == nil || p.aggregate == nil {
+		query += c.d.LimitOffset(q.limit, q.offset) + lock
+	}
 
-		if err := rows.Scan(scanDest...); err != nil {
-			return nil, err
+	return query, nil
+}
+
+// loadedColumns returns the model columns left to load by Only and Defer.
+// The primary key is always loaded.
+func (q *QuerySet[T]) loadedColumns(c *compiler) []*fieldMeta {
+	selected := func(names []string) map[string]bool {
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			if f := c.base.field(name); f != nil {
+				set[f.Column] = true
+			} else {
+				c.fail(fmt.Errorf("%s has no field %q", c.base.Type.Name(), name))
+			}
 		}
+		return set
+	}
+	only, deferred := selected(q.only), selected(q.deferred)
 
-		for i, col := range cols {
-			valPtr := scanDest[i].(*interface{})
-			val := *valPtr
+	var loaded []*fieldMeta
+	for _, f := range c.base.columns() {
+		if f.Column != "id" && ((len(only) > 0 && !only[f.Column]) || deferred[f.Column]) {
+			continue
+		}
+		loaded = append(loaded, f)
+	}
+	return loaded
+}
 
-			if val == nil {
-				continue
+// selectRelatedColumns joins the relations named in SelectRelated and
+// returns the related columns to select, aliased by path (e.g.
+// "author__name"), and the related primary keys to group by. Relations are
+// joined with LEFT JOIN when nullable, so rows without one are kept.
+func (q *QuerySet[T]) selectRelatedColumns(c *compiler) ([]string, []string, error) {
+	var columns, keys []string
+	selected := make(map[string]bool)
+
+	for _, path := range q.selectRelated {
+		meta, alias, prefix := c.base, c.alias, ""
+		for _, name := range strings.Split(path, "__") {
+			if meta == nil {
+				return nil, nil, fmt.Errorf("select_related: cannot follow %q in %q, its model is unknown", name, path)
+			}
+			f := meta.field(name)
+			if f == nil {
+				return nil, nil, fmt.Errorf("select_related: %s has no field %q", meta.Type.Name(), name)
+			}
+			rel := f.relation()
+			if rel == nil || rel.Multi || rel.Through != "" {
+				return nil, nil, fmt.Errorf("select_related: %q is not a foreign key or one-to-one relation", name)
 			}
 
-			field, found := findFieldByColumn(elem, col)
-			if found && field.CanSet() {
-				fieldVal := reflect.ValueOf(val)
-				if fieldVal.Type().ConvertibleTo(field.Type()) {
-					field.Set(fieldVal.Convert(field.Type()))
-				} else {
-					log.Printf("Warning: cannot convert %v to %v for column %s", fieldVal.Type(), field.Type(), col)
+			j := c.join(prefix+name, alias, rel, false)
+			// Only fields holding the related model can be populated
+			if j.meta != nil && structType(f.Type) != nil && !selected[j.path] {
+				selected[j.path] = true
+				for _, col := range j.meta.columns() {
+					columns = append(columns, qualify(j.alias, col.Column)+" AS "+c.d.QuoteIdent(j.path+"__"+col.Column))
 				}
+				keys = append(keys, qualify(j.alias, "id"))
 			}
+			meta, alias, prefix = j.meta, j.alias, j.path+"__"
 		}
-		results = append(results, item)
 	}
+	return columns, keys, nil
+}
 
-	// Handle PrefetchRelated after main results are fetched
-	if len(q.prefetchRelated) > 0 {
-		for _, fieldName := range q.prefetchRelated {
-			if err := q.handlePrefetch(results, fieldName); err != nil {
-				return nil, err
+// filtersRepeatRows reports whether the filters join relations that can
+// match several rows per result
+func (q *QuerySet[T]) filtersRepeatRows(c *compiler) bool {
+	scratch := newCompiler(c.d, c.base)
+	q.where(scratch)
+	return scratch.repeatsRows()
+}
+
+// where renders the queryset's filters and excludes that belong in WHERE,
+// or "" if it has none
+func (q *QuerySet[T]) where(c *compiler) string {
+	nodes, _, _ := q.filterClauses(c)
+	return strings.Join(c.conditions(nodes), " AND ")
+}
+
+// filterClauses sorts the filters and excludes, split into single
+// conditions, by what they refer to: conditions on aggregate annotations
+// belong in HAVING, conditions on window annotations are applied to the
+// computed rows, and all others belong in WHERE
+func (q *QuerySet[T]) filterClauses(c *compiler) (where, having, windowed []Node) {
+	nodes := append(append([]Node(nil), q.filters...), q.excludes...)
+	if len(q.annotations) == 0 {
+		return nodes, nil, nil
+	}
+
+	for _, n := range nodes {
+		split := []Node{n}
+		if lookups, ok := n.(Q); ok {
+			split = split[:0]
+			for _, k := range sortedLookups(lookups) {
+				split = append(split, Q{k: lookups[k]})
+			}
+		}
+		for _, n := range split {
+			scratch := newCompiler(c.d, c.base)
+			scratch.annotations = q.annotations
+			scratch.compile(n)
+			switch {
+			case scratch.windowRef:
+				windowed = append(windowed, n)
+			case scratch.aggregateRef:
+				having = append(having, n)
+			default:
+				where = append(where, n)
 			}
 		}
 	}
-
-	return results, nil
+	return where, having, windowed
 }
 
-func (q *QuerySet[T]) handlePrefetch(results []T, fieldName string) error {
-	if len(results) == 0 {
-		return nil
+// orderBy renders the ORDER BY list. Annotations are ordered by their alias,
+// which is how results are ordered by an expression.
+func (q *QuerySet[T]) orderBy(c *compiler) string {
+	parts := make([]string, 0, len(q.ordering))
+	for _, field := range q.ordering {
+		name := strings.TrimPrefix(field, "-")
+		col := c.d.QuoteIdent(name)
+		if q.annotation(name) == nil {
+			col = c.column(name, true)
+		}
+		if strings.HasPrefix(field, "-") {
+			col += " DESC"
+		}
+		parts = append(parts, col)
 	}
+	return strings.Join(parts, ", ")
+}
+
+// meta returns the metadata of the queryset's model
+func (q *QuerySet[T]) meta() *modelMeta {
+	var zero T
+	return metaOf(reflect.TypeOf(zero))
+}
 
+func (q *QuerySet[T]) getTableName() string {
 	var zero T
 	t := reflect.TypeOf(zero)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-
-	field, found := t.FieldByName(fieldName)
-	if !found {
-		return fmt.Errorf("field %s not found on model %s", fieldName, t.Name())
-	}
-
-	tag := field.Tag.Get("drf")
-	rel := getOptionValue(tag, "relation")
-	m2m := getOptionValue(tag, "m2m")
-
-	if rel == "" && m2m == "" {
-		return nil
-	}
-
-	if m2m != "" {
-		return q.handleM2M(results, fieldName, m2m, tag)
-	}
-
-	parts := strings.Split(rel, ".")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid relation tag format: %s", rel)
+	instance := reflect.New(t).Interface()
+	if m, ok := instance.(ModelInterface); ok {
+		return m.TableName()
 	}
-	return q.handleReverseFK(results, fieldName, parts[0], parts[1])
+	return ""
 }
 
-func (q *QuerySet[T]) handleM2M(results []T, fieldName, throughTable, tag string) error {
-	toCol := getOptionValue(tag, "to")
-	fromCol := getOptionValue(tag, "from")
-
-	ids := make([]interface{}, 0)
-	idMap := make(map[interface{}][]int)
-	for i, res := range results {
-		val := reflect.ValueOf(res)
-		if val.Kind() == reflect.Ptr {
-			val = val.Elem()
-		}
-		idField := val.FieldByName("ID")
-		if !idField.IsValid() {
-			continue
-		}
-		idVal := idField.Interface()
-		ids = append(ids, idVal)
-		idMap[idVal] = append(idMap[idVal], i)
+// All returns all matching records (Terminal operation)
+func (q *QuerySet[T]) All() ([]T, error) {
+	q, err := q.resolve(false)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(ids) == 0 {
-		return nil
+	if err := q.checkLocking(); err != nil {
+		return nil, err
 	}
 
-	// Find related model info to get its table name and fields
-	var zero T
-	parentType := reflect.TypeOf(zero)
-	if parentType.Kind() == reflect.Ptr {
-		parentType = parentType.Elem()
+	query, args, err := q.compile(nil)
+	if err != nil {
+		return nil, err
 	}
-	field, _ := parentType.FieldByName(fieldName)
-	sliceType := field.Type
-	elemType := sliceType.Elem()
-	if elemType.Kind() == reflect.Ptr {
-		elemType = elemType.Elem()
+	rows, err := q.db.QueryContext(q.getContext(), query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	relatedInstance := reflect.New(elemType).Interface()
-	relatedModel, ok := relatedInstance.(ModelInterface)
-	if !ok {
-		return fmt.Errorf("related model does not implement ModelInterface")
+	results, err := scanRows[T](rows)
+	if err != nil {
+		return nil, err
 	}
-	relatedTable := relatedModel.TableName()
-
-	// SELECT r.*, t.fromCol as _m2m_map_id FROM related r JOIN through t ON r.id = t.toCol WHERE t.fromCol IN (...)
-	query := fmt.Sprintf("SELECT r.*, t.%s AS _m2m_map_id FROM %s r JOIN %s t ON r.id = t.%s WHERE t.%s IN (",
-		fromCol, relatedTable, throughTable, toCol, fromCol)
 
-	placeholders := make([]string, len(ids))
-	for i := range ids {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	// Handle PrefetchRelated after main results are fetched
+	if err := q.prefetch(results); err != nil {
+		return nil, err
 	}
-	query += strings.Join(placeholders, ", ") + ")"
 
-	rows, err := q.db.Query(query, ids...)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
+	return results, nil
+}
 
+// scanRows scans every remaining row into a new T
+func scanRows[T ModelInterface](rows *db.Rows) ([]T, error) {
 	cols, err := rows.Columns()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	scanner := newRowScanner(metaOf(reflect.TypeOf((*T)(nil)).Elem()).Type, cols)
+	var results []T
 	for rows.Next() {
-		inst := reflect.New(elemType).Elem()
-		dest := make([]interface{}, len(cols))
-		var m2mMapID interface{}
-
-		for i, col := range cols {
-			if col == "_m2m_map_id" {
-				dest[i] = &m2mMapID
-				continue
-			}
-			fieldFound := false
-			for j := 0; j < inst.NumField(); j++ {
-				f := inst.Type().Field(j)
-				tag := f.Tag.Get("drf")
-				parts := strings.Split(tag, ";")
-				if parts[0] == col || f.Name == col {
-					dest[i] = inst.Field(j).Addr().Interface()
-					fieldFound = true
-					break
-				}
-			}
-			if !fieldFound {
-				var dummy interface{}
-				dest[i] = &dummy
-			}
+		var item T
+		val := reflect.ValueOf(&item).Elem()
+		if val.Kind() == reflect.Ptr {
+			val.Set(reflect.New(val.Type().Elem()))
 		}
 
-		if err := rows.Scan(dest...); err != nil {
-			return err
+		elem := val
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
 		}
 
-		if indices, ok := idMap[m2mMapID]; ok {
-			for _, idx := range indices {
-				parent := reflect.ValueOf(&results[idx]).Elem()
-				if parent.Kind() == reflect.Ptr {
-					parent = parent.Elem()
-				}
-				sliceField := parent.FieldByName(fieldName)
-				if sliceField.IsValid() && sliceField.Kind() == reflect.Slice {
-					// Handle pointer vs concrete slice
-					itemToAppend := inst
-					if sliceType.Elem().Kind() == reflect.Ptr {
-						itemToAppend = inst.Addr()
-					}
-					sliceField.Set(reflect.Append(sliceField, itemToAppend))
-				}
-			}
+		if err := scanner.scan(rows, elem); err != nil {
+			return nil, err
 		}
+		results = append(results, item)
 	}
-
-	return nil
+	return results, rows.Err()
 }
 
 // Create inserts a new record into the database
@@ -507,38 +630,43 @@ func (q *QuerySet[T]) Create(obj T) error {
 		val = val.Elem()
 	}
 
+	q, err := q.resolve(true)
+	if err != nil {
+		return err
+	}
+
 	var tableName string
 	if m, ok := interface{}(obj).(ModelInterface); ok {
 		tableName = m.TableName()
 	}
 
-	// Set timestamps
-	now := time.Now()
-	t := val.Type()
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		tag := f.Tag.Get("drf")
-		if hasOption(tag, "auto_now_add") || hasOption(tag, "auto_now") {
-			field := val.Field(i)
-			if field.CanSet() && field.Type().String() == "time.Time" {
-				field.Set(reflect.ValueOf(now))
-			}
-		}
-	}
+	setCreateTimestamps(val, time.Now())
 
+	d := q.db.Ops()
 	fields, values := collectFields(val)
 	placeholders := make([]string, len(fields))
 	for i := range fields {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		placeholders[i] = d.Placeholder(i + 1)
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id",
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		tableName, strings.Join(fields, ", "), strings.Join(placeholders, ", "))
 
 	var id uint64
-	err := q.db.QueryRow(query, values...).Scan(&id)
-	if err != nil {
-		return err
+	if d.SupportsReturning() {
+		if err := q.db.QueryRowContext(q.getContext(), query+" RETURNING id", values...).Scan(&id); err != nil {
+			return err
+		}
+	} else {
+		res, err := q.db.ExecContext(q.getContext(), query, values...)
+		if err != nil {
+			return err
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = uint64(lastID)
 	}
 
 	// Update the ID field in the object
@@ -550,110 +678,6 @@ func (q *QuerySet[T]) Create(obj T) error {
 	return nil
 }
 
-func (q *QuerySet[T]) handleReverseFK(results []T, fieldName, relatedTable, relatedCol string) error {
-	ids := make([]interface{}, 0)
-	idMap := make(map[interface{}][]int)
-	for i, res := range results {
-		val := reflect.ValueOf(res)
-		if val.Kind() == reflect.Ptr {
-			val = val.Elem()
-		}
-		idField := val.FieldByName("ID")
-		if !idField.IsValid() {
-			continue
-		}
-		idVal := idField.Interface()
-		ids = append(ids, idVal)
-		idMap[idVal] = append(idMap[idVal], i)
-	}
-
-	if len(ids) == 0 {
-		return nil
-	}
-
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (", relatedTable, relatedCol)
-	placeholders := make([]string, len(ids))
-	for i := range ids {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-	}
-	query += strings.Join(placeholders, ", ") + ")"
-
-	rows, err := q.db.Query(query, ids...)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	cols, err := rows.Columns()
-	if err != nil {
-		return err
-	}
-
-	relatedModel, err := apps.Apps.GetModel(relatedTable)
-	if err != nil {
-		return err
-	}
-	relatedType := reflect.TypeOf(relatedModel)
-	if relatedType.Kind() == reflect.Ptr {
-		relatedType = relatedType.Elem()
-	}
-
-	for rows.Next() {
-		inst := reflect.New(relatedType).Elem()
-		dest := make([]interface{}, len(cols))
-		for i, col := range cols {
-			fieldFound := false
-			for j := 0; j < inst.NumField(); j++ {
-				f := inst.Type().Field(j)
-				tag := f.Tag.Get("drf")
-				parts := strings.Split(tag, ";")
-				if parts[0] == col || f.Name == col {
-					dest[i] = inst.Field(j).Addr().Interface()
-					fieldFound = true
-					break
-				}
-			}
-			if !fieldFound {
-				var dummy interface{}
-				dest[i] = &dummy
-			}
-		}
-		if err := rows.Scan(dest...); err != nil {
-			return err
-		}
-
-		fkField := inst.FieldByName(strings.Title(relatedCol))
-		if !fkField.IsValid() {
-			for j := 0; j < inst.NumField(); j++ {
-				f := inst.Type().Field(j)
-				tag := f.Tag.Get("drf")
-				parts := strings.Split(tag, ";")
-				if parts[0] == relatedCol {
-					fkField = inst.Field(j)
-					break
-				}
-			}
-		}
-
-		if fkField.IsValid() {
-			fkVal := fkField.Interface()
-			if indices, ok := idMap[fkVal]; ok {
-				for _, idx := range indices {
-					parent := reflect.ValueOf(&results[idx]).Elem()
-					if parent.Kind() == reflect.Ptr {
-						parent = parent.Elem()
-					}
-					sliceField := parent.FieldByName(fieldName)
-					if sliceField.IsValid() && sliceField.Kind() == reflect.Slice {
-						sliceField.Set(reflect.Append(sliceField, inst))
-					}
-				}
-			}
-		}
-	}
-	return nil
-}
-
 func getOptionValue(tag, option string) string {
 	parts := strings.Split(tag, ";")
 	for _, p := range parts {
@@ -676,22 +700,43 @@ func hasOption(tag, option string) bool {
 
 // Count returns the number of results matching the query
 func (q *QuerySet[T]) Count() (int, error) {
-	sql, args := q.SQL()
-	// Replace SELECT * with SELECT COUNT(*)
-	sql = strings.Replace(sql, "SELECT *", "SELECT COUNT(*)", 1)
+	q, err := q.resolve(false)
+	if err != nil {
+		return 0, err
+	}
 
-	// Remove ORDER BY if present (not needed for COUNT and might be invalid in some dialects/situations)
-	if idx := strings.LastIndex(sql, " ORDER BY"); idx != -1 {
-		sql = sql[:idx]
+	query, args, err := q.countSQL(nil)
+	if err != nil {
+		return 0, err
 	}
 
 	var count int
-	err := q.db.QueryRow(sql, args...).Scan(&count)
+	err = q.db.QueryRowContext(q.getContext(), query, args...).Scan(&count)
 	return count, err
 }
 
+// countSQL renders a statement counting the rows q (with projection p) returns.
+// Sliced, distinct, annotated and combined querysets are counted through a
+// subquery.
+func (q *QuerySet[T]) countSQL(p *projection) (string, []interface{}, error) {
+	// Counting takes no row locks
+	q = q.clone()
+	q.forUpdate = nil
+
+	// Annotations are kept for the filters that refer to them
+	if q.limit > 0 || q.offset > 0 || q.distinct || len(q.annotations) > 0 || p != nil || q.combined != nil {
+		inner, args, err := q.compile(p)
+		if err != nil {
+			return "", nil, err
+		}
+		return "SELECT COUNT(*) FROM (" + inner + ") AS subquery", args, nil
+	}
+
+	return q.compile(&projection{aggregate: []annotation{{name: "count", expr: Count("*")}}})
+}
+
 // Get returns exactly one record (Terminal operation)
-func (q *QuerySet[T]) Get(params ...Q) (T, error) {
+func (q *QuerySet[T]) Get(params ...Node) (T, error) {
 	var zero T
 	qs := q
 	if len(params) > 0 {
@@ -704,31 +749,155 @@ func (q *QuerySet[T]) Get(params ...Q) (T, error) {
 	}
 
 	if len(results) == 0 {
-		return zero, fmt.Errorf("does not exist")
+		return zero, fmt.Errorf("%s: %w", q.getTableName(), ErrDoesNotExist)
 	}
 	if len(results) > 1 {
-		return zero, fmt.Errorf("multiple objects returned")
+		return zero, fmt.Errorf("%s: %w", q.getTableName(), ErrMultipleObjectsReturned)
 	}
 
 	return results[0], nil
 }
 
-func findFieldByColumn(v reflect.Value, colName string) (reflect.Value, bool) {
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		f := t.Field(i)
-		tag := f.Tag.Get("drf")
-		parts := strings.Split(tag, ";")
-		if parts[0] == colName {
-			return v.Field(i), true
+// Exists reports whether the queryset matches any row, fetching at most one
+func (q *QuerySet[T]) Exists() (bool, error) {
+	q, err := q.resolve(false)
+	if err != nil {
+		return false, err
+	}
+	if err := q.checkLocking(); err != nil {
+		return false, err
+	}
+
+	probe := q.clone()
+	probe.ordering = nil
+	if probe.limit == 0 {
+		probe.limit = 1
+	}
+	query, args, err := probe.compile(&projection{values: true, fields: []string{"id"}})
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := q.db.QueryContext(q.getContext(), query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	exists := rows.Next()
+	return exists, rows.Err()
+}
+
+// First returns the first object by the queryset's ordering, or by primary
+// key if it has none. It returns ErrDoesNotExist if nothing matches.
+func (q *QuerySet[T]) First() (T, error) {
+	ordered := q
+	if len(q.ordering) == 0 {
+		ordered = q.OrderBy("id")
+	}
+	return ordered.first()
+}
+
+// Last returns the last object by the queryset's ordering, or by primary
+// key if it has none. It returns ErrDoesNotExist if nothing matches.
+func (q *QuerySet[T]) Last() (T, error) {
+	ordering := q.ordering
+	if len(ordering) == 0 {
+		ordering = []string{"id"}
+	}
+	return q.OrderBy(reversed(ordering)...).first()
+}
+
+// Earliest returns the object with the smallest values of fields, e.g. a
+// date. It returns ErrDoesNotExist if nothing matches.
+func (q *QuerySet[T]) Earliest(fields ...string) (T, error) {
+	if len(fields) == 0 {
+		var zero T
+		return zero, fmt.Errorf("earliest needs at least one field")
+	}
+	return q.OrderBy(fields...).first()
+}
+
+// Latest returns the object with the largest values of fields, e.g. a date.
+// It returns ErrDoesNotExist if nothing matches.
+func (q *QuerySet[T]) Latest(fields ...string) (T, error) {
+	if len(fields) == 0 {
+		var zero T
+		return zero, fmt.Errorf("latest needs at least one field")
+	}
+	return q.OrderBy(reversed(fields)...).first()
+}
+
+func (q *QuerySet[T]) first() (T, error) {
+	var zero T
+	results, err := q.Limit(1).All()
+	if err != nil {
+		return zero, err
+	}
+	if len(results) == 0 {
+		return zero, fmt.Errorf("%s: %w", q.getTableName(), ErrDoesNotExist)
+	}
+	return results[0], nil
+}
+
+// reversed flips the direction of every ordering field
+func reversed(ordering []string) []string {
+	flipped := make([]string, len(ordering))
+	for i, field := range ordering {
+		if strings.HasPrefix(field, "-") {
+			flipped[i] = field[1:]
+		} else {
+			flipped[i] = "-" + field
 		}
-		if f.Anonymous && f.Type.Kind() == reflect.Struct {
-			if subF, ok := findFieldByColumn(v.Field(i), colName); ok {
-				return subF, true
+	}
+	return flipped
+}
+
+// InBulk returns the objects with the given primary keys, keyed by primary
+// key. Ids without a row are missing from the map.
+func (q *QuerySet[T]) InBulk(ids []uint64) (map[uint64]T, error) {
+	objs := make(map[uint64]T, len(ids))
+	for _, batch := range batches(len(ids), 1, 0) {
+		results, err := q.Filter(Q{"id__in": ids[batch[0]:batch[1]]}).All()
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range results {
+			objs[structValue(obj).FieldByName("ID").Uint()] = obj
+		}
+	}
+	return objs, nil
+}
+
+// setField assigns a scanned value to a model field, converting between
+// numeric types and parsing numbers drivers return as text. A foreign key
+// field holding the related model gets the related primary key.
+func setField(field reflect.Value, val interface{}) bool {
+	if model := structType(field.Type()); model != nil {
+		if _, ok := model.FieldByName("ID"); !ok {
+			return false
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				field.Set(reflect.New(model))
 			}
+			field = field.Elem()
 		}
+		return setField(field.FieldByName("ID"), val)
+	}
+
+	if _, isBytes := val.([]byte); isBytes && field.Kind() != reflect.String && field.Kind() != reflect.Slice {
+		val = normalize(val)
+	}
+	fieldVal := reflect.ValueOf(val)
+	if !fieldVal.Type().ConvertibleTo(field.Type()) {
+		return false
+	}
+	// Converting numbers to strings would yield runes, not digits
+	if field.Kind() == reflect.String && fieldVal.Kind() != reflect.String {
+		return false
 	}
-	return reflect.Value{}, false
+	field.Set(fieldVal.Convert(field.Type()))
+	return true
 }
 
 func collectFields(v reflect.Value) ([]string, []interface{}) {
@@ -747,17 +916,55 @@ func collectFields(v reflect.Value) ([]string, []interface{}) {
 			continue
 		}
 
-		if tag == "" || hasOption(tag, "auto_increment") || hasOption(tag, "relation") || hasOption(tag, "m2m") {
+		if tag == "" || tag == "-" || hasOption(tag, "auto_increment") || hasOption(tag, "relation") || hasOption(tag, "m2m") || hasOption(tag, "annotation") {
 			continue
 		}
 
 		colName := strings.Split(tag, ";")[0]
 		fields = append(fields, colName)
-		values = append(values, v.Field(i).Interface())
+		values = append(values, columnValue(v.Field(i), tag))
 	}
 	return fields, values
 }
 
+// setCreateTimestamps sets auto_now_add and auto_now fields of a new object
+func setCreateTimestamps(val reflect.Value, now time.Time) {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("drf")
+		if hasOption(tag, "auto_now_add") || hasOption(tag, "auto_now") {
+			field := val.Field(i)
+			if field.CanSet() && field.Type().String() == "time.Time" {
+				field.Set(reflect.ValueOf(now))
+			}
+		}
+	}
+}
+
+// columnValue returns the value stored for a field. Foreign keys holding the
+// related model store its primary key, or NULL for a nil pointer.
+func columnValue(field reflect.Value, tag string) interface{} {
+	isFK := hasOption(tag, "foreign_key") || hasOption(tag, "one_to_one")
+	if !isFK || structType(field.Type()) == nil {
+		// Hand database/sql a pointer when only it implements driver.Valuer
+		if field.CanAddr() && !field.Type().Implements(valuerType) && field.Addr().Type().Implements(valuerType) {
+			return field.Addr().Interface()
+		}
+		return field.Interface()
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	if id := field.FieldByName("ID"); id.IsValid() {
+		return id.Interface()
+	}
+	return field.Interface()
+}
+
 // Update updates records in the database
 func (q *QuerySet[T]) Update(obj T) error {
 	val := reflect.ValueOf(obj)
@@ -765,6 +972,11 @@ func (q *QuerySet[T]) Update(obj T) error {
 		val = val.Elem()
 	}
 
+	q, err := q.resolve(true)
+	if err != nil {
+		return err
+	}
+
 	var tableName string
 	if m, ok := interface{}(obj).(ModelInterface); ok {
 		tableName = m.TableName()
@@ -784,26 +996,90 @@ func (q *QuerySet[T]) Update(obj T) error {
 	fields, values := collectFields(val)
 
 	// Build SET clause
+	d := q.db.Ops()
 	setClauses := make([]string, len(fields))
 	for i, field := range fields {
-		setClauses[i] = fmt.Sprintf("%s = $%d", field, i+1)
+		setClauses[i] = fmt.Sprintf("%s = %s", field, d.Placeholder(i+1))
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d",
-		tableName, strings.Join(setClauses, ", "), len(values)+1)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = %s",
+		tableName, strings.Join(setClauses, ", "), d.Placeholder(len(values)+1))
 
 	values = append(values, id)
 
-	_, err := q.db.Exec(query, values...)
+	_, err = q.db.ExecContext(q.getContext(), query, values...)
 	return err
 }
 
-// Delete deletes a record by ID
-func (q *QuerySet[T]) Delete(id uint64) error {
-	tableName := q.getTableName()
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tableName)
-	_, err := q.db.Exec(query, id)
-	return err
+// UpdateFields sets columns on every row matching the queryset in a single
+// UPDATE and returns the number of rows affected. Values may be plain values
+// or expressions such as F("views").Add(1), which the database evaluates
+// atomically per row. Keys name fields of the model, by column or Go name.
+// Model hooks and auto_now fields are not applied.
+func (q *QuerySet[T]) UpdateFields(values map[string]interface{}) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+	if q.limit > 0 || q.offset > 0 {
+		return 0, fmt.Errorf("cannot update a queryset once a limit or offset has been applied")
+	}
+	if q.combined != nil {
+		return 0, fmt.Errorf("cannot update a combined queryset")
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return 0, err
+	}
+
+	// Keys are resolved to the model's columns, never pasted into the SQL
+	meta := q.meta()
+	byColumn := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		f := meta.field(name)
+		if f == nil || hasOption(f.Tag, "relation") || hasOption(f.Tag, "m2m") || hasOption(f.Tag, "annotation") {
+			return 0, fmt.Errorf("%s has no field %q", meta.Type.Name(), name)
+		}
+		if _, dup := byColumn[f.Column]; dup {
+			return 0, fmt.Errorf("field %q is set more than once", f.Column)
+		}
+		byColumn[f.Column] = v
+	}
+	columns := make([]string, 0, len(byColumn))
+	for col := range byColumn {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	c := newCompiler(q.db.Ops(), meta)
+	setClauses := make([]string, len(columns))
+	for i, col := range columns {
+		setClauses[i] = fmt.Sprintf("%s = %s", col, c.value(byColumn[col]))
+	}
+
+	if len(c.joins) > 0 {
+		return 0, fmt.Errorf("cannot update using values of related rows")
+	}
+
+	// UPDATE can't join, so filters across relations select ids in a subquery
+	where := q.where
+	if scratch := newCompiler(c.d, c.base); q.where(scratch) != "" && len(scratch.joins) > 0 {
+		where = func(c *compiler) string { return c.subselect(q.where) }
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s", q.getTableName(), strings.Join(setClauses, ", "))
+	if cond := where(c); cond != "" {
+		query += " WHERE " + cond
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	res, err := q.db.ExecContext(q.getContext(), query, c.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
 // GetByID retrieves a single object by ID (convenience method)
@@ -1,8 +1,19 @@
 package queryset
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/core/settings"
+	"github.com/anuragcarret/djang-drf-go/orm/connections"
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+	"github.com/anuragcarret/djang-drf-go/orm/db/dbtest"
 )
 
 type MockUser struct {
@@ -58,12 +69,52 @@ func TestQuerySetLookups(t *testing.T) {
 			filter:   Q{"age__lt": 65},
 			expected: "age < $1",
 		},
+		{
+			name:     "ne lookup",
+			filter:   Q{"age__ne": 30},
+			expected: "age <> $1",
+		},
+		{
+			name:     "istartswith lookup",
+			filter:   Q{"username__istartswith": "adm"},
+			expected: "username ILIKE $1",
+		},
+		{
+			name:     "isnull lookup",
+			filter:   Q{"email__isnull": false},
+			expected: "email IS NOT NULL",
+		},
+		{
+			name:     "exact nil lookup",
+			filter:   Q{"email": nil},
+			expected: "email IS NULL",
+		},
+		{
+			name:     "range lookup",
+			filter:   Q{"age__range": []int{18, 65}},
+			expected: "age BETWEEN $1 AND $2",
+		},
+		{
+			name:     "iregex lookup",
+			filter:   Q{"username__iregex": "^ad"},
+			expected: "username ~* $1",
+		},
+		{
+			name:     "transform with lookup",
+			filter:   Q{"created_at__year__gte": 2024},
+			expected: "EXTRACT(YEAR FROM mock_users.created_at) >= $1",
+		},
+		{
+			name:     "date transform",
+			filter:   Q{"created_at__date": "2024-01-31"},
+			expected: "CAST(mock_users.created_at AS DATE) = $1",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			q := qs.Filter(tt.filter)
-			sql, _ := q.SQL()
+			sql, _, _ := q.SQL()
 			if !strings.Contains(sql, tt.expected) {
 				t.Errorf("expected SQL to contain %q, but got %q", tt.expected, sql)
 			}
@@ -71,6 +122,59 @@ func TestQuerySetLookups(t *testing.T) {
 	}
 }
 
+func TestQComposition(t *testing.T) {
+	qs := &QuerySet[MockUser]{}
+
+	tests := []struct {
+		name     string
+		qs       *QuerySet[MockUser]
+		expected string
+		args     []interface{}
+	}{
+		{
+			name:     "or",
+			qs:       qs.Filter(Or(Q{"username": "a"}, Q{"username": "b"})),
+			expected: "WHERE (mock_users.username = $1 OR mock_users.username = $2)",
+			args:     []interface{}{"a", "b"},
+		},
+		{
+			name:     "not",
+			qs:       qs.Filter(Not(Q{"age__lt": 18})),
+			expected: "WHERE NOT (mock_users.age < $1)",
+			args:     []interface{}{18},
+		},
+		{
+			name:     "nested with plain lookups",
+			qs:       qs.Filter(Q{"age__gte": 18}, Or(Q{"username": "a"}, And(Q{"username": "b"}, Not(Q{"id__in": []int{1, 2}})))),
+			expected: "WHERE mock_users.age >= $1 AND (mock_users.username = $2 OR (mock_users.username = $3 AND NOT (mock_users.id IN ($4, $5))))",
+			args:     []interface{}{18, "a", "b", 1, 2},
+		},
+		{
+			name:     "exclude",
+			qs:       qs.Filter(Q{"age__gte": 18}).Exclude(Q{"username": "root", "id": 1}).Exclude(Or(Q{"age": 30}, Q{"age": 40})),
+			expected: "WHERE mock_users.age >= $1 AND NOT (mock_users.id = $2 AND mock_users.username = $3) AND NOT (mock_users.age = $4 OR mock_users.age = $5)",
+			args:     []interface{}{18, 1, "root", 30, 40},
+		},
+		{
+			name:     "empty nodes are ignored",
+			qs:       qs.Filter(Or(), And(Q{}), Not(Or())),
+			expected: "SELECT mock_users.* FROM mock_users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, _ := tt.qs.SQL()
+			if !strings.HasSuffix(sql, tt.expected) {
+				t.Errorf("expected SQL ending in %q, got %q", tt.expected, sql)
+			}
+			if len(tt.args) > 0 && fmt.Sprint(args) != fmt.Sprint(tt.args) {
+				t.Errorf("expected args %v, got %v", tt.args, args)
+			}
+		})
+	}
+}
+
 type MockPost struct {
 	ID      uint64 `drf:"id;primary_key"`
 	Author  uint64 `drf:"author_id;foreign_key=mock_users.id"`
@@ -84,7 +188,7 @@ func TestSelectRelatedSQL(t *testing.T) {
 
 	t.Run("basic join", func(t *testing.T) {
 		q := qs.SelectRelated("Author")
-		sql, _ := q.SQL()
+		sql, _, _ := q.SQL()
 		expected := "INNER JOIN mock_users ON mock_posts.author_id = mock_users.id"
 		if !strings.Contains(sql, expected) {
 			t.Errorf("expected SQL to contain JOIN, but got %q", sql)
@@ -96,3 +200,402 @@ func TestPrefetchRelated(t *testing.T) {
 	// This test will require a mock DB that can return multiple results for separate queries
 	// For now, we verify that handlePrefetch is called correctly and attempts to fetch
 }
+
+type liteArticle struct {
+	ID        uint64    `drf:"id;primary_key;auto_increment"`
+	Title     string    `drf:"title;max_length=100"`
+	Views     int64     `drf:"views"`
+	Published bool      `drf:"published"`
+	CreatedAt time.Time `drf:"created_at;auto_now_add"`
+}
+
+func (a *liteArticle) TableName() string { return "lite_articles" }
+
+func newSQLiteDB(t testing.TB) *db.DB {
+	t.Helper()
+	database, err := db.NewDB("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	database := newSQLiteDB(t)
+	if err := database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL",
+		"published":  "BOOLEAN NOT NULL",
+		"created_at": "TIMESTAMP WITH TIME ZONE",
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	qs := NewQuerySet[*liteArticle](database)
+	for i, title := range []string{"Go Generics", "SQLite Tips", "Postgres Tuning"} {
+		a := &liteArticle{Title: title, Views: int64(i * 10), Published: i != 1}
+		if err := qs.Create(a); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if a.ID != uint64(i+1) {
+			t.Errorf("expected ID %d from LastInsertId, got %d", i+1, a.ID)
+		}
+	}
+
+	sql, _, _ := qs.Filter(Q{"title__icontains": "go"}).SQL()
+	if !strings.Contains(sql, "title LIKE ?1") {
+		t.Errorf("expected sqlite placeholders and LIKE, got %q", sql)
+	}
+
+	results, err := qs.Filter(Q{"title__icontains": "GO"}).OrderBy("-views").All()
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "Go Generics" {
+		t.Errorf("unexpected icontains results: %+v", results)
+	}
+
+	paged, err := qs.OrderBy("id").Offset(1).All()
+	if err != nil {
+		t.Fatalf("Offset without Limit failed: %v", err)
+	}
+	if len(paged) != 2 || paged[0].ID != 2 {
+		t.Errorf("unexpected offset results: %+v", paged)
+	}
+
+	obj, err := qs.GetByID(2)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if obj.Published || obj.CreatedAt.IsZero() {
+		t.Errorf("expected bool and timestamp to round-trip, got %+v", obj)
+	}
+
+	obj.Title = "SQLite Tricks"
+	if err := qs.Update(obj); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, _, err := qs.Filter(Q{"id": 1}).Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	remaining, _ := qs.OrderBy("id").All()
+	if len(remaining) != 2 || remaining[0].Title != "SQLite Tricks" {
+		t.Errorf("unexpected rows after update/delete: %+v", remaining)
+	}
+}
+
+func TestQuerySetWithTx(t *testing.T) {
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL",
+		"published":  "BOOLEAN NOT NULL",
+		"created_at": "TIMESTAMP",
+	})
+
+	qs := NewQuerySet[*liteArticle](database)
+	err := database.Atomic(context.Background(), func(tx *db.DB) error {
+		if err := qs.WithTx(tx).Create(&liteArticle{Title: "draft"}); err != nil {
+			return err
+		}
+		results, err := qs.WithTx(tx).All()
+		if err != nil {
+			return err
+		}
+		if len(results) != 1 {
+			t.Errorf("expected uncommitted row to be visible inside the transaction, got %d", len(results))
+		}
+		return errors.New("abort")
+	})
+	if err == nil || err.Error() != "abort" {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	results, err := qs.All()
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected rollback to discard the row, got %d", len(results))
+	}
+}
+
+func TestQuerySetWithContext(t *testing.T) {
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_articles", map[string]string{
+		"id":    "SERIAL PRIMARY KEY",
+		"title": "VARCHAR(100) NOT NULL",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	qs := NewQuerySet[*liteArticle](database).WithContext(ctx)
+	if _, err := qs.All(); !errors.Is(err, context.Canceled) {
+		t.Errorf("All: expected context.Canceled, got %v", err)
+	}
+	if err := qs.Create(&liteArticle{Title: "x"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create: expected context.Canceled, got %v", err)
+	}
+	if _, err := qs.Filter(Q{"id": 1}).Get(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get: expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQuerySetRouting(t *testing.T) {
+	newArticleDB := func() *db.DB {
+		database := newSQLiteDB(t)
+		database.CreateTable("lite_articles", map[string]string{
+			"id":         "SERIAL PRIMARY KEY",
+			"title":      "VARCHAR(100) NOT NULL",
+			"views":      "BIGINT NOT NULL DEFAULT 0",
+			"published":  "BOOLEAN NOT NULL DEFAULT FALSE",
+			"created_at": "TIMESTAMP WITH TIME ZONE",
+		})
+		return database
+	}
+	primary, replica := newArticleDB(), newArticleDB()
+	replica.Exec("INSERT INTO lite_articles (title) VALUES ('replicated')")
+
+	archive := filepath.Join(t.TempDir(), "archive.db")
+	saved := connections.Connections
+	connections.Connections = connections.NewHandler(map[string]settings.DatabaseConfig{
+		"archive": {Engine: "sqlite3", Name: archive},
+	})
+	t.Cleanup(func() { connections.Connections = saved })
+	connections.Connections.Register("default", primary)
+	connections.Connections.Register("replica", replica)
+	connections.SetRouters(&connections.ReplicaRouter{Primary: "default", Replicas: []string{"replica"}})
+
+	ctx := connections.WithPinning(context.Background())
+	qs := NewQuerySet[*liteArticle](nil).WithContext(ctx)
+
+	results, err := qs.All()
+	if err != nil || len(results) != 1 || results[0].Title != "replicated" {
+		t.Fatalf("expected read from replica, got %v (err: %v)", results, err)
+	}
+
+	if err := qs.Create(&liteArticle{Title: "fresh"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	results, err = qs.All()
+	if err != nil || len(results) != 1 || results[0].Title != "fresh" {
+		t.Errorf("expected pinned read from primary after write, got %v (err: %v)", results, err)
+	}
+
+	results, err = NewQuerySet[*liteArticle](nil).Using("replica").All()
+	if err != nil || len(results) != 1 {
+		t.Errorf("Using(replica): expected 1 row, got %d (err: %v)", len(results), err)
+	}
+	if _, err := NewQuerySet[*liteArticle](nil).Using("missing").All(); !errors.Is(err, connections.ErrConnectionNotFound) {
+		t.Errorf("expected ErrConnectionNotFound, got %v", err)
+	}
+
+	// SQL renders in the alias's dialect without opening a connection
+	sql, _, err := NewQuerySet[*liteArticle](nil).Using("archive").Filter(Q{"title": "old"}).SQL()
+	if err != nil || !strings.HasSuffix(sql, "WHERE lite_articles.title = ?1") {
+		t.Errorf("expected sqlite SQL, got %q (err: %v)", sql, err)
+	}
+	if _, err := os.Stat(archive); err == nil {
+		t.Error("expected SQL not to open the archive database")
+	}
+	if _, _, err := NewQuerySet[*liteArticle](nil).Using("missing").SQL(); !errors.Is(err, connections.ErrConnectionNotFound) {
+		t.Errorf("expected SQL to report ErrConnectionNotFound, got %v", err)
+	}
+}
+
+func TestQuerySetQueryCount(t *testing.T) {
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL DEFAULT 0",
+		"published":  "BOOLEAN NOT NULL DEFAULT FALSE",
+		"created_at": "TIMESTAMP WITH TIME ZONE",
+	})
+	qs := NewQuerySet[*liteArticle](database)
+
+	dbtest.AssertNumQueries(t, 3, func() {
+		qs.Create(&liteArticle{Title: "a"})
+		qs.Create(&liteArticle{Title: "b"})
+		qs.Filter(Q{"title": "a"}).All()
+	})
+	events := dbtest.AssertMaxQueries(t, 1, func() {
+		qs.Get(Q{"title": "b"})
+	})
+	if len(events) == 1 && !strings.HasPrefix(events[0].SQL, "SELECT lite_articles.*") {
+		t.Errorf("unexpected captured SQL %q", events[0].SQL)
+	}
+}
+
+func TestQCompositionSQLite(t *testing.T) {
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL DEFAULT 0",
+		"published":  "BOOLEAN NOT NULL DEFAULT FALSE",
+		"created_at": "TIMESTAMP WITH TIME ZONE",
+	})
+	qs := NewQuerySet[*liteArticle](database)
+	for i, title := range []string{"Go Generics", "SQLite Tips", "Postgres Tuning", "Go Testing"} {
+		qs.Create(&liteArticle{Title: title, Views: int64(i * 10), Published: i%2 == 0})
+	}
+
+	titles := func(qs *QuerySet[*liteArticle]) string {
+		results, err := qs.OrderBy("id").All()
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Title)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := titles(qs.Filter(Or(Q{"title__icontains": "sqlite"}, Q{"views__gte": 30}))); got != "SQLite Tips,Go Testing" {
+		t.Errorf("Or: got %q", got)
+	}
+	if got := titles(qs.Exclude(Q{"title__contains": "Go"})); got != "SQLite Tips,Postgres Tuning" {
+		t.Errorf("Exclude: got %q", got)
+	}
+	if got := titles(qs.Filter(Q{"published": true}, Not(Or(Q{"views": 0}, Q{"title": "nope"})))); got != "Postgres Tuning" {
+		t.Errorf("nested Not/Or: got %q", got)
+	}
+}
+
+func TestFExpressions(t *testing.T) {
+	qs := &QuerySet[MockUser]{}
+	sql, args, _ := qs.Filter(Q{"age__gt": F("id").Mul(2).Add(1)}).SQL()
+	if !strings.HasSuffix(sql, "WHERE mock_users.age > ((mock_users.id * $1) + $2)") || len(args) != 2 {
+		t.Errorf("unexpected SQL %q with args %v", sql, args)
+	}
+
+	database := newSQLiteDB(t)
+	database.CreateTable("lite_articles", map[string]string{
+		"id":         "SERIAL PRIMARY KEY",
+		"title":      "VARCHAR(100) NOT NULL",
+		"views":      "BIGINT NOT NULL DEFAULT 0",
+		"published":  "BOOLEAN NOT NULL DEFAULT FALSE",
+		"created_at": "TIMESTAMP WITH TIME ZONE",
+	})
+	articles := NewQuerySet[*liteArticle](database)
+	for i, title := range []string{"a", "b", "c"} {
+		articles.Create(&liteArticle{Title: title, Views: int64(i * 10)})
+	}
+
+	n, err := articles.Filter(Q{"views__gte": 10}).UpdateFields(map[string]interface{}{
+		"views":     F("views").Add(1),
+		"published": true,
+	})
+	if err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows affected, got %d", n)
+	}
+
+	results, _ := articles.OrderBy("id").All()
+	var views []int64
+	for _, r := range results {
+		views = append(views, r.Views)
+		if r.Published != (r.Views > 0) {
+			t.Errorf("unexpected published flag on %q", r.Title)
+		}
+	}
+	if fmt.Sprint(views) != "[0 11 21]" {
+		t.Errorf("expected views [0 11 21], got %v", views)
+	}
+
+	if n, _ := articles.Filter(Q{"views__gt": F("id").Mul(5)}).UpdateFields(map[string]interface{}{"title": "hot"}); n != 2 {
+		t.Errorf("expected F in filters to compare per row, got %d rows", n)
+	}
+	if _, err := articles.Limit(1).UpdateFields(map[string]interface{}{"views": 0}); err == nil {
+		t.Error("expected an error updating a limited queryset")
+	}
+
+	if n, err := articles.Filter(Q{"title": "a"}).UpdateFields(map[string]interface{}{"Views": 5}); err != nil || n != 1 {
+		t.Errorf("expected Go field names to resolve to columns, got %d, %v", n, err)
+	}
+	for _, key := range []string{"viewz", "views = 0, title", "title = 'x' --"} {
+		if _, err := articles.UpdateFields(map[string]interface{}{key: 1}); err == nil || !strings.Contains(err.Error(), "has no field") {
+			t.Errorf("expected an error for the unknown field %q, got %v", key, err)
+		}
+	}
+}
+
+func TestTerminalShortcuts(t *testing.T) {
+	database := newLibraryDB(t)
+	authors := NewQuerySet[*liteAuthor](database)
+	books := NewQuerySet[*liteBook](database)
+
+	t.Run("Get returns sentinel errors", func(t *testing.T) {
+		if _, err := authors.Get(Q{"name": "Zed"}); !errors.Is(err, ErrDoesNotExist) {
+			t.Errorf("expected ErrDoesNotExist, got %v", err)
+		}
+		if _, err := books.Get(Q{"author_id": 1}); !errors.Is(err, ErrMultipleObjectsReturned) {
+			t.Errorf("expected ErrMultipleObjectsReturned, got %v", err)
+		}
+	})
+
+	t.Run("Exists fetches at most one row", func(t *testing.T) {
+		events := dbtest.AssertNumQueries(t, 1, func() {
+			if ok, err := books.Filter(Q{"pages__gt": 150}).Exists(); err != nil || !ok {
+				t.Errorf("expected long books to exist, got %v %v", ok, err)
+			}
+		})
+		if sql := events[0].SQL; !strings.HasPrefix(sql, `SELECT lite_books.id AS "id" FROM`) || !strings.HasSuffix(sql, "LIMIT 1") {
+			t.Errorf("unexpected Exists SQL %q", sql)
+		}
+		if ok, err := books.Filter(Q{"pages__gt": 1000}).Exists(); err != nil || ok {
+			t.Errorf("expected no books over 1000 pages, got %v %v", ok, err)
+		}
+	})
+
+	t.Run("First and Last follow the ordering", func(t *testing.T) {
+		if a, err := authors.First(); err != nil || a.Name != "Ann" {
+			t.Errorf("expected Ann first by id, got %+v %v", a, err)
+		}
+		if a, err := authors.Last(); err != nil || a.Name != "Cid" {
+			t.Errorf("expected Cid last by id, got %+v %v", a, err)
+		}
+		if a, err := authors.OrderBy("-name").Last(); err != nil || a.Name != "Ann" {
+			t.Errorf("expected Ann last by -name, got %+v %v", a, err)
+		}
+		if _, err := authors.Filter(Q{"name": "Zed"}).First(); !errors.Is(err, ErrDoesNotExist) {
+			t.Errorf("expected ErrDoesNotExist, got %v", err)
+		}
+	})
+
+	t.Run("Earliest and Latest order by fields", func(t *testing.T) {
+		if b, err := books.Earliest("pages"); err != nil || b.Title != "Go" {
+			t.Errorf("expected Go to be shortest, got %+v %v", b, err)
+		}
+		if b, err := books.Latest("pages"); err != nil || b.Title != "SQL" {
+			t.Errorf("expected SQL to be longest, got %+v %v", b, err)
+		}
+		if _, err := books.Latest(); err == nil {
+			t.Error("expected an error without fields")
+		}
+	})
+
+	t.Run("InBulk maps ids to objects", func(t *testing.T) {
+		found, err := authors.InBulk([]uint64{1, 3, 9})
+		if err != nil {
+			t.Fatalf("InBulk failed: %v", err)
+		}
+		if len(found) != 2 || found[1].Name != "Ann" || found[3].Name != "Cid" {
+			t.Errorf("expected Ann and Cid, got %v", found)
+		}
+		dbtest.AssertNumQueries(t, 0, func() {
+			if found, err := authors.InBulk(nil); err != nil || len(found) != 0 {
+				t.Errorf("expected an empty map, got %v %v", found, err)
+			}
+		})
+	})
+}
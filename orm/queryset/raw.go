@@ -0,0 +1,111 @@
+package queryset
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// RawQuerySet maps the rows of a hand-written query onto T. Columns are
+// matched to fields by column name, aliases such as "author__name" fill the
+// related structs as with SelectRelated, and columns matching no field are
+// passed to models implementing ExtraColumns.
+type RawQuerySet[T ModelInterface] struct {
+	qs    *QuerySet[T]
+	query string
+	args  []interface{}
+}
+
+// Raw runs query with args on database, or with a nil database on the
+// connection the routers choose for reads:
+//
+//	authors, err := Raw[*Author](database, `SELECT authors.*, COUNT(books.id) AS book_count
+//		FROM authors LEFT JOIN books ON books.author_id = authors.id
+//		GROUP BY authors.id HAVING COUNT(books.id) > $1`, 2).PrefetchRelated("books").All()
+func Raw[T ModelInterface](database *db.DB, query string, args ...interface{}) *RawQuerySet[T] {
+	return &RawQuerySet[T]{qs: NewQuerySet[T](database), query: query, args: args}
+}
+
+func (r *RawQuerySet[T]) with(qs *QuerySet[T]) *RawQuerySet[T] {
+	return &RawQuerySet[T]{qs: qs, query: r.query, args: r.args}
+}
+
+// WithContext sets the context used to run the query
+func (r *RawQuerySet[T]) WithContext(ctx context.Context) *RawQuerySet[T] {
+	return r.with(r.qs.WithContext(ctx))
+}
+
+// Using runs the query on the named connection, bypassing routers
+func (r *RawQuerySet[T]) Using(alias string) *RawQuerySet[T] {
+	return r.with(r.qs.Using(alias))
+}
+
+// PrefetchRelated loads relations of the results as QuerySet.PrefetchRelated
+// does, once the rows have been read
+func (r *RawQuerySet[T]) PrefetchRelated(lookups ...interface{}) *RawQuerySet[T] {
+	return r.with(r.qs.PrefetchRelated(lookups...))
+}
+
+// SQL returns the query and its arguments
+func (r *RawQuerySet[T]) SQL() (string, []interface{}) {
+	return r.query, r.args
+}
+
+// All returns every row of the query (Terminal operation)
+func (r *RawQuerySet[T]) All() ([]T, error) {
+	q, err := r.qs.resolve(false)
+	if err != nil {
+		return nil, err
+	}
+	return q.fetch(r.query, r.args...)
+}
+
+// Iterator streams the rows of the query, holding at most chunkSize of them
+// in memory and prefetching relations once per chunk. Databases with
+// server-side cursors read through one, as QuerySet.Iterator does; others
+// page through the query by offset, which is only reliable when its ORDER BY
+// is deterministic (e.g. ends with the primary key). Otherwise rows may be
+// skipped or repeated between chunks.
+func (r *RawQuerySet[T]) Iterator(chunkSize int) iter.Seq2[T, error] {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		q, err := r.qs.resolve(false)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		d := q.db.Ops()
+		if d.SupportsCursors() {
+			if err := q.iterateCursor(r.query, r.args, chunkSize, yield); err != nil {
+				yield(zero, err)
+			}
+			return
+		}
+
+		// A trailing semicolon would end the statement inside the subquery
+		inner := strings.TrimRight(strings.TrimSpace(r.query), ";")
+		for offset := 0; ; offset += chunkSize {
+			chunk, err := q.fetch(fmt.Sprintf("SELECT * FROM (%s) AS raw%s", inner, d.LimitOffset(chunkSize, offset)), r.args...)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, obj := range chunk {
+				if !yield(obj, nil) {
+					return
+				}
+			}
+			if len(chunk) < chunkSize {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package queryset
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db/dbtest"
+)
+
+// liteReportAuthor is lite_authors with the extra columns of a report
+type liteReportAuthor struct {
+	ID    uint64                 `drf:"id;primary_key;auto_increment"`
+	Name  string                 `drf:"name;max_length=100"`
+	Extra map[string]interface{} `drf:"-"`
+}
+
+func (a *liteReportAuthor) TableName() string { return "lite_authors" }
+
+func (a *liteReportAuthor) SetExtraColumns(values map[string]interface{}) { a.Extra = values }
+
+func TestRaw(t *testing.T) {
+	database := newShelfDB(t)
+
+	t.Run("maps fields, annotations and extra columns", func(t *testing.T) {
+		authors, err := Raw[*liteReportAuthor](database, `SELECT lite_authors.*, COUNT(lite_books.id) AS book_count,
+			MAX(lite_books.title) AS last_title FROM lite_authors
+			LEFT JOIN lite_books ON lite_books.author_id = lite_authors.id
+			GROUP BY lite_authors.id HAVING COUNT(lite_books.id) >= ?1 ORDER BY lite_authors.id`, 1).All()
+		if err != nil {
+			t.Fatalf("Raw failed: %v", err)
+		}
+		if len(authors) != 2 || authors[0].Name != "Ann" || authors[1].Name != "Bob" {
+			t.Fatalf("unexpected authors %+v", authors)
+		}
+		expected := map[string]interface{}{"profile_id": int64(1), "book_count": int64(2), "last_title": "SQL"}
+		if !reflect.DeepEqual(authors[0].Extra, expected) {
+			t.Errorf("expected extra columns %v, got %v", expected, authors[0].Extra)
+		}
+
+		counted, err := Raw[*liteAuthor](database, "SELECT lite_authors.*, 7 AS book_count FROM lite_authors").All()
+		if err != nil || len(counted) != 3 || counted[0].BookCount != 7 {
+			t.Errorf("expected the annotation field to be loaded, got %+v, %v", counted, err)
+		}
+	})
+
+	t.Run("fills related structs from aliases", func(t *testing.T) {
+		novels, err := Raw[*liteNovel](database, `SELECT lite_books.*, lite_authors.name AS author__name
+			FROM lite_books JOIN lite_authors ON lite_authors.id = lite_books.author_id WHERE lite_books.id = ?1`, 3).All()
+		if err != nil {
+			t.Fatalf("Raw failed: %v", err)
+		}
+		if len(novels) != 1 || novels[0].Author == nil || novels[0].Author.ID != 2 || novels[0].Author.Name != "Bob" {
+			t.Errorf("expected Rust by Bob, got %+v", novels)
+		}
+	})
+
+	t.Run("prefetches and iterates in chunks", func(t *testing.T) {
+		raw := Raw[*liteShelfAuthor](database, "SELECT * FROM lite_authors ORDER BY name DESC;").PrefetchRelated("books")
+
+		var books []int
+		events := dbtest.AssertNumQueries(t, 4, func() {
+			for a, err := range raw.Iterator(2) {
+				if err != nil {
+					t.Fatalf("Iterator failed: %v", err)
+				}
+				books = append(books, len(a.Books))
+			}
+		})
+		if !reflect.DeepEqual(books, []int{0, 1, 2}) {
+			t.Errorf("expected Cid, Bob and Ann's books, got %v", books)
+		}
+		if want := "SELECT * FROM (SELECT * FROM lite_authors ORDER BY name DESC) AS raw LIMIT 2 OFFSET 2"; events[2].SQL != want {
+			t.Errorf("expected the second chunk query %q, got %q", want, events[2].SQL)
+		}
+
+		all, err := raw.All()
+		if err != nil || len(all) != 3 || len(all[2].Books) != 2 {
+			t.Errorf("expected All to prefetch too, got %+v, %v", all, err)
+		}
+	})
+}
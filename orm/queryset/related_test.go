@@ -0,0 +1,155 @@
+package queryset
+
+import (
+	"reflect"
+	"testing"
+)
+
+type liteProfile struct {
+	ID  uint64 `drf:"id;primary_key;auto_increment"`
+	Bio string `drf:"bio"`
+}
+
+func (p *liteProfile) TableName() string { return "lite_profiles" }
+
+// liteWriter is lite_authors with its nullable profile relation
+type liteWriter struct {
+	ID      uint64       `drf:"id;primary_key;auto_increment"`
+	Name    string       `drf:"name;max_length=100"`
+	Profile *liteProfile `drf:"profile_id;foreign_key=lite_profiles.id;null"`
+}
+
+func (w *liteWriter) TableName() string { return "lite_authors" }
+
+// liteNovel is lite_books with its author relation
+type liteNovel struct {
+	ID     uint64      `drf:"id;primary_key;auto_increment"`
+	Title  string      `drf:"title;max_length=100"`
+	Pages  int64       `drf:"pages"`
+	Author *liteWriter `drf:"author_id;foreign_key=lite_authors.id"`
+}
+
+func (n *liteNovel) TableName() string { return "lite_books" }
+
+func TestSelectRelatedColumnsSQL(t *testing.T) {
+	sql, _, _ := (&QuerySet[*liteNovel]{}).SelectRelated("author__profile").SQL()
+	expected := `SELECT lite_books.*, lite_authors.id AS "author__id", lite_authors.name AS "author__name", ` +
+		`lite_authors.profile_id AS "author__profile_id", lite_profiles.id AS "author__profile__id", ` +
+		`lite_profiles.bio AS "author__profile__bio" FROM lite_books ` +
+		`LEFT JOIN lite_authors ON lite_books.author_id = lite_authors.id ` +
+		`LEFT JOIN lite_profiles ON lite_authors.profile_id = lite_profiles.id`
+	if sql != expected {
+		t.Errorf("\n got %q\nwant %q", sql, expected)
+	}
+
+	if _, err := NewQuerySet[*liteNovel](newSQLiteDB(t)).SelectRelated("title").All(); err == nil {
+		t.Error("expected an error selecting a plain field")
+	}
+}
+
+func TestSelectRelated(t *testing.T) {
+	database := newLibraryDB(t)
+
+	novels, err := NewQuerySet[*liteNovel](database).SelectRelated("author__profile").OrderBy("id").All()
+	if err != nil {
+		t.Fatalf("SelectRelated failed: %v", err)
+	}
+	if len(novels) != 3 {
+		t.Fatalf("expected 3 books, got %d", len(novels))
+	}
+	ann := novels[0].Author
+	if ann == nil || ann.Name != "Ann" || ann.Profile == nil || ann.Profile.Bio != "Writes about Go" {
+		t.Errorf("expected Ann with her profile, got %+v", ann)
+	}
+	bob := novels[2].Author
+	if bob == nil || bob.Name != "Bob" || bob.Profile != nil {
+		t.Errorf("expected Bob without a profile, got %+v", bob)
+	}
+
+	// Rows whose nullable relation is missing are kept
+	writers, err := NewQuerySet[*liteWriter](database).SelectRelated("profile").OrderBy("name").All()
+	if err != nil || len(writers) != 3 {
+		t.Fatalf("expected all 3 writers, got %d (%v)", len(writers), err)
+	}
+	if writers[0].Profile == nil || writers[1].Profile != nil || writers[2].Profile != nil {
+		t.Errorf("unexpected profiles: %+v %+v %+v", writers[0].Profile, writers[1].Profile, writers[2].Profile)
+	}
+
+	// Foreign keys holding the related model save its primary key
+	novel := &liteNovel{Title: "Zig", Pages: 50, Author: writers[2]}
+	if err := NewQuerySet[*liteNovel](database).Create(novel); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if n, _ := NewQuerySet[*liteBook](database).Filter(Q{"author__name": "Cid"}).Count(); n != 1 {
+		t.Errorf("expected Cid to have 1 book, got %d", n)
+	}
+}
+
+// liteScribe is lite_authors with a helper field that isn't stored
+type liteScribe struct {
+	ID   uint64 `drf:"id;primary_key;auto_increment"`
+	Name string `drf:"name;max_length=100"`
+	Note string
+}
+
+func (s *liteScribe) TableName() string { return "lite_authors" }
+
+// liteDraft is lite_books with a helper field that isn't stored
+type liteDraft struct {
+	ID     uint64      `drf:"id;primary_key;auto_increment"`
+	Title  string      `drf:"title;max_length=100"`
+	Pages  int64       `drf:"pages"`
+	Author *liteScribe `drf:"author_id;foreign_key=lite_authors.id"`
+	Note   string
+}
+
+func (d *liteDraft) TableName() string { return "lite_books" }
+
+func TestUntaggedFields(t *testing.T) {
+	if f := metaOf(reflect.TypeOf(liteDraft{})).field("note"); f != nil {
+		t.Errorf("expected untagged fields not to be columns, got %+v", f)
+	}
+
+	drafts, err := NewQuerySet[*liteDraft](newLibraryDB(t)).Defer("pages").SelectRelated("author").OrderBy("id").All()
+	if err != nil {
+		t.Fatalf("Defer and SelectRelated failed: %v", err)
+	}
+	if len(drafts) != 3 || drafts[0].Title != "Go" || drafts[0].Author == nil || drafts[0].Author.Name != "Ann" {
+		t.Errorf("unexpected drafts: %+v", drafts)
+	}
+}
+
+func TestExcludeNullable(t *testing.T) {
+	writers := NewQuerySet[*liteWriter](newLibraryDB(t))
+
+	sql, _, _ := writers.Exclude(Q{"profile": 1}).SQL()
+	expected := "SELECT lite_authors.* FROM lite_authors WHERE NOT (lite_authors.profile_id = ?1 AND lite_authors.profile_id IS NOT NULL)"
+	if sql != expected {
+		t.Errorf("\n got %q\nwant %q", sql, expected)
+	}
+
+	names := func(qs *QuerySet[*liteWriter]) []string {
+		t.Helper()
+		results, err := qs.OrderBy("name").All()
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		var names []string
+		for _, w := range results {
+			names = append(names, w.Name)
+		}
+		return names
+	}
+	if got := names(writers.Exclude(Q{"profile": 1})); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("expected the writers without a profile to be kept, got %v", got)
+	}
+	if got := names(writers.Exclude(Q{"profile__bio__contains": "Go"})); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("expected the writers without a profile to be kept, got %v", got)
+	}
+	if got := names(writers.Filter(Not(Not(Q{"profile__bio__contains": "Go"})))); !reflect.DeepEqual(got, []string{"Ann"}) {
+		t.Errorf("expected a double negation to match Ann only, got %v", got)
+	}
+	if got := names(writers.Exclude(Q{"name": "Ann"})); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("unexpected exclude on a required column: %v", got)
+	}
+}
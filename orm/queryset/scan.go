@@ -0,0 +1,203 @@
+package queryset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// ExtraColumns is implemented by models that keep the columns of a row
+// matching none of their fields, such as the computed values of a Raw
+// query or an Annotate without an annotation field
+type ExtraColumns interface {
+	SetExtraColumns(values map[string]interface{})
+}
+
+var (
+	scannerType      = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType       = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	extraColumnsType = reflect.TypeOf((*ExtraColumns)(nil)).Elem()
+)
+
+// scanPlan maps the columns of a result set onto the fields of a model type
+type scanPlan struct {
+	columns []columnPlan
+	// extra is set when the model implements ExtraColumns
+	extra bool
+}
+
+// columnPlan says where one column is scanned
+type columnPlan struct {
+	name string
+	// hops index the select_related fields leading to the struct holding
+	// the column, for aliases such as "author__name"
+	hops [][]int
+	// index is the field's index in that struct, nil if no field matches
+	index []int
+	// direct fields are handed to database/sql as they are: sql.Scanner
+	// implementations and pointers, which NULL sets to nil
+	direct bool
+}
+
+type planKey struct {
+	t    reflect.Type
+	cols string
+}
+
+var planCache sync.Map // planKey -> *scanPlan
+
+// planFor returns the plan for scanning cols into the model struct t,
+// building it on first use
+func planFor(t reflect.Type, cols []string) *scanPlan {
+	key := planKey{t: t, cols: strings.Join(cols, "\x00")}
+	if p, ok := planCache.Load(key); ok {
+		return p.(*scanPlan)
+	}
+
+	p := &scanPlan{
+		columns: make([]columnPlan, len(cols)),
+		extra:   reflect.PointerTo(t).Implements(extraColumnsType),
+	}
+	for i, col := range cols {
+		p.columns[i] = planColumn(t, col)
+	}
+	actual, _ := planCache.LoadOrStore(key, p)
+	return actual.(*scanPlan)
+}
+
+func planColumn(t reflect.Type, col string) columnPlan {
+	cp := columnPlan{name: col}
+	path := strings.Split(col, "__")
+	for _, name := range path[:len(path)-1] {
+		f := metaOf(t).field(name)
+		if f == nil || structType(f.Type) == nil || f.Type.Kind() == reflect.Slice {
+			return cp
+		}
+		cp.hops = append(cp.hops, f.Index)
+		t = structType(f.Type)
+	}
+
+	for _, f := range metaOf(t).Fields {
+		if f.Column != path[len(path)-1] || hasOption(f.Tag, "relation") || hasOption(f.Tag, "m2m") {
+			continue
+		}
+		cp.index = f.Index
+		cp.direct = reflect.PointerTo(f.Type).Implements(scannerType) ||
+			(f.Type.Kind() == reflect.Ptr && structType(f.Type) == nil)
+		break
+	}
+	return cp
+}
+
+// rowScanner scans rows into model structs following a plan, reusing its
+// scan destinations from row to row
+type rowScanner struct {
+	plan *scanPlan
+	dest []interface{}
+	// elem is the struct the current row is scanned into
+	elem reflect.Value
+}
+
+// newRowScanner prepares to scan rows with columns cols into structs of type t
+func newRowScanner(t reflect.Type, cols []string) *rowScanner {
+	rs := &rowScanner{plan: planFor(t, cols), dest: make([]interface{}, len(cols))}
+	for i := range rs.plan.columns {
+		cp := &rs.plan.columns[i]
+		switch {
+		case cp.index == nil && rs.plan.extra && !strings.Contains(cp.name, "__"):
+			rs.dest[i] = new(interface{})
+		case cp.index == nil:
+			rs.dest[i] = discard{}
+		case !cp.direct || len(cp.hops) > 0:
+			rs.dest[i] = &fieldScanner{row: rs, column: cp}
+		}
+	}
+	return rs
+}
+
+// scan scans the current row into the model struct elem
+func (rs *rowScanner) scan(rows *db.Rows, elem reflect.Value) error {
+	rs.elem = elem
+	for i, cp := range rs.plan.columns {
+		if cp.direct && len(cp.hops) == 0 {
+			rs.dest[i] = elem.FieldByIndex(cp.index).Addr().Interface()
+		}
+	}
+	if err := rows.Scan(rs.dest...); err != nil {
+		return err
+	}
+
+	if !rs.plan.extra {
+		return nil
+	}
+	var extra map[string]interface{}
+	for i, cp := range rs.plan.columns {
+		if v, ok := rs.dest[i].(*interface{}); ok {
+			if extra == nil {
+				extra = make(map[string]interface{})
+			}
+			extra[cp.name] = normalize(*v)
+		}
+	}
+	if extra != nil {
+		elem.Addr().Interface().(ExtraColumns).SetExtraColumns(extra)
+	}
+	return nil
+}
+
+// fieldScanner scans a column into a model field, leaving the field at its
+// zero value (and a select_related struct unloaded) for NULL
+type fieldScanner struct {
+	row    *rowScanner
+	column *columnPlan
+}
+
+func (s *fieldScanner) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	v := s.row.elem
+	for _, hop := range s.column.hops {
+		v = v.FieldByIndex(hop)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	return assign(v.FieldByIndex(s.column.index), src)
+}
+
+// assign stores a scanned value in field
+func assign(field reflect.Value, src interface{}) error {
+	if s, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return s.Scan(src)
+	}
+	if field.Kind() == reflect.Ptr && structType(field.Type()) == nil {
+		v := reflect.New(field.Type().Elem())
+		if err := assign(v.Elem(), src); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+	if v := reflect.ValueOf(src); v.Type() == field.Type() {
+		field.Set(v)
+		return nil
+	}
+	if !setField(field, src) {
+		return fmt.Errorf("cannot convert %T to %v", src, field.Type())
+	}
+	return nil
+}
+
+// discard is the scan destination of columns nothing reads
+type discard struct{}
+
+func (discard) Scan(interface{}) error { return nil }
@@ -0,0 +1,96 @@
+package queryset
+
+import "fmt"
+
+// Subqueryable is a queryset that can be nested in another query: as the
+// value of an "__in" lookup, or wrapped by Subquery or Exists. A QuerySet
+// selects its primary keys; Values and ValuesList querysets select their
+// single field.
+//
+//	active := NewQuerySet[*Author](nil).Filter(Q{"is_active": true})
+//	posts.Filter(Q{"author_id__in": active})
+type Subqueryable interface {
+	subquery(c *compiler) string
+}
+
+func (q *QuerySet[T]) subquery(c *compiler) string {
+	return q.nestedSQL(c, "id")
+}
+
+func (v *ValuesQuerySet[T]) subquery(c *compiler) string {
+	if len(v.fields) != 1 {
+		c.fail(fmt.Errorf("a subquery must select exactly one field, got %d", len(v.fields)))
+		return ""
+	}
+	return v.qs.nestedSQL(c, v.fields[0])
+}
+
+func (v *ValuesListQuerySet[T]) subquery(c *compiler) string {
+	return v.values.subquery(c)
+}
+
+// nestedSQL renders q as a parenthesized subquery of c's statement
+// selecting only field
+func (q *QuerySet[T]) nestedSQL(c *compiler, field string) string {
+	inner := q.clone()
+	inner.annotations = nil
+	if a := q.annotation(field); a != nil {
+		inner.annotations = []annotation{*a}
+	}
+
+	sub := c.nested(q.meta())
+	query, err := inner.compileWith(sub, &projection{values: true, fields: []string{field}})
+	c.args = sub.args
+	if err != nil {
+		c.fail(err)
+	}
+	return "(" + query + ")"
+}
+
+// OuterRef refers to a field of the query enclosing a subquery, correlating
+// the subquery with each outer row:
+//
+//	unpaid := NewQuerySet[*Invoice](nil).Filter(Q{"user_id": OuterRef("id"), "paid": false})
+//	users.Filter(Exists(unpaid))
+type OuterRef string
+
+func (r OuterRef) sql(c *compiler) string {
+	if c.outer == nil {
+		c.fail(fmt.Errorf("OuterRef(%q) is only valid in a subquery", string(r)))
+		return ""
+	}
+	return c.outer.column(string(r), false)
+}
+
+// SubqueryExpression is a subquery used as a single value, e.g. in an
+// annotation or an exact lookup. It must return at most one row.
+type SubqueryExpression struct {
+	Query Subqueryable
+}
+
+// Subquery uses the value returned by qs:
+//
+//	latest := NewQuerySet[*Post](nil).Filter(Q{"author_id": OuterRef("id")}).OrderBy("-created_at").ValuesList("title").Limit(1)
+//	authors.Annotate(map[string]Expression{"latest_title": Subquery(latest)})
+func Subquery(qs Subqueryable) *SubqueryExpression {
+	return &SubqueryExpression{Query: qs}
+}
+
+func (s *SubqueryExpression) sql(c *compiler) string { return s.Query.subquery(c) }
+
+func (s *SubqueryExpression) subquery(c *compiler) string { return s.Query.subquery(c) }
+
+// ExistsExpression is true when its subquery returns any row. It can be
+// passed to Filter, Exclude, And, Or and Not, or annotated.
+type ExistsExpression struct {
+	Query Subqueryable
+}
+
+// Exists tests whether qs matches any row, usually correlated with OuterRef
+func Exists(qs Subqueryable) *ExistsExpression {
+	return &ExistsExpression{Query: qs}
+}
+
+func (*ExistsExpression) node() {}
+
+func (e *ExistsExpression) sql(c *compiler) string { return "EXISTS " + e.Query.subquery(c) }
@@ -0,0 +1,112 @@
+package queryset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubquerySQL(t *testing.T) {
+	books := &QuerySet[*liteBook]{}
+	authors := &QuerySet[*liteAuthor]{}
+
+	tests := []struct {
+		name     string
+		sql      func() (string, []interface{}, error)
+		expected string
+		args     []interface{}
+	}{
+		{
+			name: "queryset as an in value",
+			sql: books.Filter(Q{"pages__gt": 150}).
+				Filter(Q{"author_id__in": authors.Filter(Q{"name": "Ann"})}).SQL,
+			expected: `SELECT lite_books.* FROM lite_books WHERE lite_books.pages > $1 AND lite_books.author_id IN (SELECT lite_authors.id AS "id" FROM lite_authors WHERE lite_authors.name = $2)`,
+			args:     []interface{}{150, "Ann"},
+		},
+		{
+			name:     "values queryset selects its field",
+			sql:      authors.Filter(Q{"id__in": books.Filter(Q{"pages__lt": 150}).ValuesList("author_id")}).SQL,
+			expected: `SELECT lite_authors.* FROM lite_authors WHERE lite_authors.id IN (SELECT lite_books.author_id AS "author_id" FROM lite_books WHERE lite_books.pages < $1)`,
+			args:     []interface{}{150},
+		},
+		{
+			name:     "same table gets an alias",
+			sql:      authors.Filter(Q{"id__in": authors.Filter(Q{"name": "Ann"})}).SQL,
+			expected: `SELECT lite_authors.* FROM lite_authors WHERE lite_authors.id IN (SELECT lite_authors2.id AS "id" FROM lite_authors lite_authors2 WHERE lite_authors2.name = $1)`,
+			args:     []interface{}{"Ann"},
+		},
+		{
+			name: "exists correlated with OuterRef",
+			sql: authors.Filter(Q{"name__icontains": "a"}).
+				Filter(Exists(books.Filter(Q{"author_id": OuterRef("id"), "pages__gt": 150}))).SQL,
+			expected: `SELECT lite_authors.* FROM lite_authors WHERE lite_authors.name ILIKE $1 ESCAPE '\' AND EXISTS (SELECT lite_books.id AS "id" FROM lite_books WHERE lite_books.author_id = lite_authors.id AND lite_books.pages > $2)`,
+			args:     []interface{}{"%a%", 150},
+		},
+		{
+			name:     "negated exists",
+			sql:      authors.Filter(Not(Exists(books.Filter(Q{"author_id": OuterRef("id")})))).SQL,
+			expected: `SELECT lite_authors.* FROM lite_authors WHERE NOT (EXISTS (SELECT lite_books.id AS "id" FROM lite_books WHERE lite_books.author_id = lite_authors.id))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.sql()
+			if err != nil || sql != tt.expected {
+				t.Errorf("expected SQL:\n%s\ngot:\n%s (%v)", tt.expected, sql, err)
+			}
+			if len(tt.args) > 0 && !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("expected args %v, got %v", tt.args, args)
+			}
+		})
+	}
+}
+
+func TestSubqueries(t *testing.T) {
+	database := newLibraryDB(t)
+	authors := NewQuerySet[*liteAuthor](database)
+	books := NewQuerySet[*liteBook](database)
+
+	names := func(qs *QuerySet[*liteAuthor]) []string {
+		t.Helper()
+		results, err := qs.OrderBy("name").All()
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		var out []string
+		for _, a := range results {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+
+	long := books.Filter(Q{"author_id": OuterRef("id"), "pages__gt": 150})
+	if got := names(authors.Filter(Exists(long))); !reflect.DeepEqual(got, []string{"Ann", "Bob"}) {
+		t.Errorf("expected Ann and Bob to have long books, got %v", got)
+	}
+	if got := names(authors.Exclude(Exists(books.Filter(Q{"author_id": OuterRef("id")})))); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("expected only Cid without books, got %v", got)
+	}
+	if got := names(authors.Filter(Q{"id__in": books.Filter(Q{"title": "Rust"}).ValuesList("author_id")})); !reflect.DeepEqual(got, []string{"Bob"}) {
+		t.Errorf("expected Bob to have written Rust, got %v", got)
+	}
+
+	longest := books.Filter(Q{"author_id": OuterRef("id")}).OrderBy("-pages").ValuesList("title").Limit(1)
+	rows, err := authors.Values("name").Annotate(map[string]Expression{"longest": Subquery(longest)}).OrderBy("name").All()
+	if err != nil {
+		t.Fatalf("annotating a subquery failed: %v", err)
+	}
+	var longestByAuthor []interface{}
+	for _, row := range rows {
+		longestByAuthor = append(longestByAuthor, row["longest"])
+	}
+	if !reflect.DeepEqual(longestByAuthor, []interface{}{"SQL", "Rust", nil}) {
+		t.Errorf("expected each author's longest book, got %v", longestByAuthor)
+	}
+
+	if _, err := authors.Filter(Q{"id": OuterRef("id")}).All(); err == nil {
+		t.Error("expected an error for OuterRef outside a subquery")
+	}
+	if _, err := authors.Filter(Q{"id__in": books.Values("id", "title")}).All(); err == nil {
+		t.Error("expected an error for a subquery selecting two fields")
+	}
+}
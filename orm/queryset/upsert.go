@@ -0,0 +1,279 @@
+package queryset
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+// GetOrCreate returns the object matching lookup, creating it from lookup's
+// exact values and defaults if there is none. The bool reports whether it
+// was created. If a concurrent writer creates the row first, the insert
+// fails on the unique constraint covering lookup and the winner's row is
+// returned instead.
+//
+//	token, created, err := qs.GetOrCreate(Q{"jti": jti}, map[string]interface{}{"user_id": uid})
+func (q *QuerySet[T]) GetOrCreate(lookup Q, defaults map[string]interface{}) (T, bool, error) {
+	var zero T
+	q, err := q.resolve(true)
+	if err != nil {
+		return zero, false, err
+	}
+
+	obj, err := q.Get(lookup)
+	if err == nil {
+		return obj, false, nil
+	}
+	if !errors.Is(err, ErrDoesNotExist) {
+		return zero, false, err
+	}
+
+	obj, err = q.newObject(lookup, defaults)
+	if err != nil {
+		return zero, false, err
+	}
+	// The insert runs in a savepoint when q is bound to a transaction, so a
+	// lost race doesn't abort it
+	createErr := q.db.Atomic(q.getContext(), func(tx *db.DB) error {
+		return q.WithTx(tx).Create(obj)
+	})
+	if createErr == nil {
+		return obj, true, nil
+	}
+	if existing, err := q.Get(lookup); err == nil {
+		return existing, false, nil
+	}
+	return zero, false, createErr
+}
+
+// UpdateOrCreate updates the object matching lookup with defaults, or
+// creates it from lookup's exact values and defaults. The bool reports
+// whether it was created. The existing row is locked while it is updated,
+// so concurrent calls don't overwrite each other's changes.
+func (q *QuerySet[T]) UpdateOrCreate(lookup Q, defaults map[string]interface{}) (T, bool, error) {
+	var zero T
+	q, err := q.resolve(true)
+	if err != nil {
+		return zero, false, err
+	}
+
+	var obj T
+	var created bool
+	err = q.db.Atomic(q.getContext(), func(tx *db.DB) error {
+		scoped := q.WithTx(tx).SelectForUpdate(ForUpdate{Of: []string{"self"}})
+		var err error
+		obj, created, err = scoped.GetOrCreate(lookup, defaults)
+		if err != nil || created {
+			return err
+		}
+		if err := assignValues(structValue(obj), q.meta(), defaults); err != nil {
+			return err
+		}
+		return scoped.Update(obj)
+	})
+	if err != nil {
+		return zero, false, err
+	}
+	return obj, created, nil
+}
+
+// newObject builds a T from the exact lookups in lookup and defaults, which
+// take precedence
+func (q *QuerySet[T]) newObject(lookup Q, defaults map[string]interface{}) (T, error) {
+	var obj T
+	val := reflect.ValueOf(&obj).Elem()
+	if val.Kind() == reflect.Ptr {
+		val.Set(reflect.New(val.Type().Elem()))
+		val = val.Elem()
+	}
+
+	values := map[string]interface{}{}
+	for key, v := range lookup {
+		// Lookups such as "age__gte" don't say what to store
+		if name := strings.TrimSuffix(key, "__exact"); !strings.Contains(name, "__") {
+			values[name] = v
+		}
+	}
+	for key, v := range defaults {
+		values[key] = v
+	}
+	if err := assignValues(val, q.meta(), values); err != nil {
+		return obj, err
+	}
+	return obj, nil
+}
+
+// assignValues sets the fields of the model struct val named by values'
+// keys, which may be columns or Go field names
+func assignValues(val reflect.Value, meta *modelMeta, values map[string]interface{}) error {
+	for name, v := range values {
+		f := meta.field(name)
+		if f == nil {
+			return fmt.Errorf("%s has no field %q", meta.Table, name)
+		}
+		if _, ok := v.(Expression); ok {
+			return fmt.Errorf("cannot assign an expression to %s.%s", meta.Table, f.Column)
+		}
+		field := val.FieldByIndex(f.Index)
+		if v == nil {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		// Plain values go through the scan path, which fills pointer and
+		// nullable fields
+		if rv := reflect.ValueOf(v); rv.Type().AssignableTo(field.Type()) {
+			field.Set(rv)
+		} else if err := assign(field, v); err != nil {
+			return fmt.Errorf("cannot assign %T to %s.%s", v, meta.Table, f.Column)
+		}
+	}
+	return nil
+}
+
+// OnConflict configures what Upsert does when the row collides with an
+// existing one
+type OnConflict struct {
+	// Columns is the conflict target: columns covered by a unique
+	// constraint or the primary key
+	Columns []string
+	// Update lists the columns overwritten with the new row's values,
+	// defaulting to every inserted column outside Columns except the
+	// primary key and auto_now_add columns
+	Update []string
+	// DoNothing keeps the existing row unchanged
+	DoNothing bool
+}
+
+// Upsert inserts obj, or on a conflict over conflict.Columns updates (or
+// leaves) the existing row, using INSERT ... ON CONFLICT. It sets obj's ID
+// in either case and reports whether a row was created.
+//
+//	created, err := qs.Upsert(rate, OnConflict{Columns: []string{"currency"}, Update: []string{"value"}})
+func (q *QuerySet[T]) Upsert(obj T, conflict OnConflict) (bool, error) {
+	if len(conflict.Columns) == 0 {
+		return false, fmt.Errorf("upsert needs conflict columns")
+	}
+
+	q, err := q.resolve(true)
+	if err != nil {
+		return false, err
+	}
+
+	val := structValue(obj)
+	setCreateTimestamps(val, time.Now())
+	columns, values := collectFields(val)
+
+	// The existing row is found by the conflict columns' new values
+	target := Q{}
+	for _, col := range conflict.Columns {
+		i := indexOf(columns, col)
+		if i < 0 {
+			return false, fmt.Errorf("conflict column %q is not inserted", col)
+		}
+		target[col] = values[i]
+	}
+
+	update := conflict.Update
+	if len(update) == 0 && !conflict.DoNothing {
+		meta := q.meta()
+		for _, col := range columns {
+			// The existing row keeps its primary key and creation time
+			if f := meta.field(col); f != nil && (hasOption(f.Tag, "primary_key") || hasOption(f.Tag, "auto_now_add")) {
+				continue
+			}
+			if indexOf(conflict.Columns, col) < 0 {
+				update = append(update, col)
+			}
+		}
+	}
+	action := "DO NOTHING"
+	if !conflict.DoNothing && len(update) > 0 {
+		sets := make([]string, len(update))
+		for i, col := range update {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+		}
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	d := q.db.Ops()
+	c := newCompiler(d, nil)
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = c.param(v)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		q.getTableName(), strings.Join(columns, ", "), strings.Join(placeholders, ", "),
+		strings.Join(conflict.Columns, ", "), action)
+
+	ctx := q.getContext()
+	idField := val.FieldByName("ID")
+	var created bool
+	err = q.db.Atomic(ctx, func(tx *db.DB) error {
+		scoped := q.WithTx(tx)
+
+		if flag := d.InsertedFlag(); flag != "" {
+			var id uint64
+			err := tx.QueryRowContext(ctx, query+" RETURNING id, "+flag, c.args...).Scan(&id, &created)
+			if err == nil {
+				idField.SetUint(id)
+				return nil
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			// DO NOTHING returns no row on a conflict
+			return scoped.loadID(idField, target)
+		}
+
+		existing, err := scoped.Filter(target).ValuesList("id").Flat()
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, query, c.args...)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			setField(idField, existing[0])
+			return nil
+		}
+		created = true
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		idField.SetUint(uint64(id))
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// loadID sets idField to the id of the row matching target
+func (q *QuerySet[T]) loadID(idField reflect.Value, target Q) error {
+	ids, err := q.Filter(target).ValuesList("id").Flat()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrDoesNotExist
+	}
+	setField(idField, ids[0])
+	return nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,130 @@
+package queryset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anuragcarret/djang-drf-go/orm/db"
+)
+
+type liteRate struct {
+	ID       uint64  `drf:"id;primary_key;auto_increment"`
+	Currency string  `drf:"currency;unique"`
+	Value    float64 `drf:"value"`
+	Source   string  `drf:"source"`
+	Note     *string `drf:"note;null"`
+	// CreatedAt is set when the rate is first stored
+	CreatedAt time.Time `drf:"created_at;auto_now_add"`
+}
+
+func (r *liteRate) TableName() string { return "lite_rates" }
+
+// newRatesDB creates a USD rate of 1.0 from "ecb"
+func newRatesDB(t *testing.T) *db.DB {
+	t.Helper()
+	database := newSQLiteDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE lite_rates (id INTEGER PRIMARY KEY AUTOINCREMENT, currency TEXT NOT NULL UNIQUE, value REAL NOT NULL, source TEXT NOT NULL, note TEXT, created_at TIMESTAMP)",
+		"INSERT INTO lite_rates (currency, value, source) VALUES ('USD', 1.0, 'ecb')",
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return database
+}
+
+func TestGetOrCreate(t *testing.T) {
+	rates := NewQuerySet[*liteRate](newRatesDB(t))
+
+	rate, created, err := rates.GetOrCreate(Q{"currency": "USD"}, map[string]interface{}{"value": 2.0})
+	if err != nil || created || rate.ID != 1 || rate.Value != 1.0 {
+		t.Errorf("expected the existing USD rate, got %+v created=%v err=%v", rate, created, err)
+	}
+
+	rate, created, err = rates.GetOrCreate(Q{"currency": "EUR"}, map[string]interface{}{"value": 0.9, "source": "ecb"})
+	if err != nil || !created || rate.ID != 2 || rate.Value != 0.9 || rate.Source != "ecb" {
+		t.Errorf("expected a new EUR rate, got %+v created=%v err=%v", rate, created, err)
+	}
+
+	// The lookup misses, so the insert runs and hits the unique constraint
+	if _, _, err := rates.GetOrCreate(Q{"currency": "usd"}, map[string]interface{}{"currency": "USD"}); err == nil {
+		t.Error("expected the unique constraint to fail the create")
+	}
+
+	rate, created, err = rates.GetOrCreate(Q{"currency": "CAD"}, map[string]interface{}{"note": "estimate"})
+	if err != nil || !created || rate.Note == nil || *rate.Note != "estimate" {
+		t.Errorf("expected the note default on a nullable field, got %+v created=%v err=%v", rate, created, err)
+	}
+
+	if _, _, err := rates.GetOrCreate(Q{"currency": "GBP"}, map[string]interface{}{"rate": 1.2}); err == nil {
+		t.Error("expected an error for an unknown field in defaults")
+	}
+}
+
+func TestUpdateOrCreate(t *testing.T) {
+	rates := NewQuerySet[*liteRate](newRatesDB(t))
+
+	rate, created, err := rates.UpdateOrCreate(Q{"currency": "USD"}, map[string]interface{}{"value": 1.1})
+	if err != nil || created || rate.ID != 1 || rate.Value != 1.1 {
+		t.Errorf("expected the USD rate updated, got %+v created=%v err=%v", rate, created, err)
+	}
+	if stored, _ := rates.GetByID(1); stored.Value != 1.1 || stored.Source != "ecb" {
+		t.Errorf("expected the update to be saved, got %+v", stored)
+	}
+
+	if _, _, err := rates.UpdateOrCreate(Q{"currency": "USD"}, map[string]interface{}{"note": "revised"}); err != nil {
+		t.Fatalf("UpdateOrCreate failed: %v", err)
+	}
+	if stored, _ := rates.GetByID(1); stored.Note == nil || *stored.Note != "revised" {
+		t.Errorf("expected the note to be set, got %+v", stored.Note)
+	}
+
+	rate, created, err = rates.UpdateOrCreate(Q{"currency": "JPY"}, map[string]interface{}{"value": 150.0})
+	if err != nil || !created || rate.ID != 2 {
+		t.Errorf("expected a new JPY rate, got %+v created=%v err=%v", rate, created, err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	rates := NewQuerySet[*liteRate](newRatesDB(t))
+	byCurrency := OnConflict{Columns: []string{"currency"}, Update: []string{"value"}}
+
+	usd := &liteRate{Currency: "USD", Value: 1.2, Source: "fed"}
+	created, err := rates.Upsert(usd, byCurrency)
+	if err != nil || created || usd.ID != 1 {
+		t.Fatalf("expected the USD rate updated in place, got %+v created=%v err=%v", usd, created, err)
+	}
+	if stored, _ := rates.GetByID(1); stored.Value != 1.2 || stored.Source != "ecb" {
+		t.Errorf("expected only value to be updated, got %+v", stored)
+	}
+
+	chf := &liteRate{Currency: "CHF", Value: 0.8, Source: "snb"}
+	if created, err := rates.Upsert(chf, byCurrency); err != nil || !created || chf.ID == 0 {
+		t.Errorf("expected CHF to be inserted, got %+v created=%v err=%v", chf, created, err)
+	}
+
+	stale := &liteRate{Currency: "CHF", Value: 0.1, Source: "old"}
+	if created, err := rates.Upsert(stale, OnConflict{Columns: []string{"currency"}, DoNothing: true}); err != nil || created || stale.ID != chf.ID {
+		t.Errorf("expected the CHF row to be left alone, got %+v created=%v err=%v", stale, created, err)
+	}
+	if stored, _ := rates.GetByID(chf.ID); stored.Value != 0.8 {
+		t.Errorf("expected DO NOTHING to keep the row, got %+v", stored)
+	}
+
+	all := &liteRate{Currency: "CHF", Value: 0.85, Source: "ecb"}
+	if _, err := rates.Upsert(all, OnConflict{Columns: []string{"currency"}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	stored, _ := rates.GetByID(chf.ID)
+	if stored.Value != 0.85 || stored.Source != "ecb" {
+		t.Errorf("expected every column to be updated by default, got %+v", stored)
+	}
+	if !stored.CreatedAt.Equal(chf.CreatedAt) || stored.CreatedAt.Equal(all.CreatedAt) {
+		t.Errorf("expected created_at to survive the conflict, got %v (inserted %v)", stored.CreatedAt, chf.CreatedAt)
+	}
+
+	if _, err := rates.Upsert(&liteRate{Currency: "AUD"}, OnConflict{Columns: []string{"code"}}); err == nil {
+		t.Error("expected an error for a conflict column that is not inserted")
+	}
+}
@@ -0,0 +1,216 @@
+package queryset
+
+import "fmt"
+
+// ValuesQuerySet returns rows as maps of field name to value instead of
+// model structs. Combined with Annotate it groups by the selected fields:
+//
+//	qs.Values("author_id").Annotate(map[string]Expression{"books": Count("*")})
+type ValuesQuerySet[T ModelInterface] struct {
+	qs     *QuerySet[T]
+	fields []string
+}
+
+// Values returns the given fields (all columns if none) of each result.
+// Fields may follow relations with "__", e.g. "author__name".
+func (q *QuerySet[T]) Values(fields ...string) *ValuesQuerySet[T] {
+	return &ValuesQuerySet[T]{qs: q.clone(), fields: fields}
+}
+
+func (v *ValuesQuerySet[T]) with(qs *QuerySet[T]) *ValuesQuerySet[T] {
+	return &ValuesQuerySet[T]{qs: qs, fields: v.fields}
+}
+
+// Filter adds positive filter criteria
+func (v *ValuesQuerySet[T]) Filter(nodes ...Node) *ValuesQuerySet[T] {
+	return v.with(v.qs.Filter(nodes...))
+}
+
+// Exclude adds negative filter criteria
+func (v *ValuesQuerySet[T]) Exclude(nodes ...Node) *ValuesQuerySet[T] {
+	return v.with(v.qs.Exclude(nodes...))
+}
+
+// Annotate adds computed values to each row. Aggregates are computed per
+// distinct combination of the selected fields.
+func (v *ValuesQuerySet[T]) Annotate(annotations map[string]Expression) *ValuesQuerySet[T] {
+	return v.with(v.qs.Annotate(annotations))
+}
+
+// OrderBy sets the sort order; annotations can be ordered by name
+func (v *ValuesQuerySet[T]) OrderBy(fields ...string) *ValuesQuerySet[T] {
+	return v.with(v.qs.OrderBy(fields...))
+}
+
+// Limit sets the maximum number of rows
+func (v *ValuesQuerySet[T]) Limit(n int) *ValuesQuerySet[T] {
+	return v.with(v.qs.Limit(n))
+}
+
+// Offset sets the number of rows to skip
+func (v *ValuesQuerySet[T]) Offset(n int) *ValuesQuerySet[T] {
+	return v.with(v.qs.Offset(n))
+}
+
+func (v *ValuesQuerySet[T]) projection() *projection {
+	return &projection{values: true, fields: v.fields}
+}
+
+// SQL returns the generated SQL query and arguments, without connecting to
+// the database
+func (v *ValuesQuerySet[T]) SQL() (string, []interface{}, error) {
+	return v.qs.render(v.projection())
+}
+
+// All returns the matching rows (Terminal operation)
+func (v *ValuesQuerySet[T]) All() ([]map[string]interface{}, error) {
+	q, err := v.qs.resolve(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.checkLocking(); err != nil {
+		return nil, err
+	}
+
+	query, args, err := q.compile(v.projection())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(q.getContext(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		dest := make([]interface{}, len(cols))
+		for i := range dest {
+			dest[i] = new(interface{})
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			row[col] = normalize(*dest[i].(*interface{}))
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// Count returns the number of rows
+func (v *ValuesQuerySet[T]) Count() (int, error) {
+	q, err := v.qs.resolve(false)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err := q.countSQL(v.projection())
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = q.db.QueryRowContext(q.getContext(), query, args...).Scan(&count)
+	return count, err
+}
+
+// ValuesListQuerySet returns rows as slices of values in the order of the
+// selected fields, or with Flat a single list of values
+type ValuesListQuerySet[T ModelInterface] struct {
+	values *ValuesQuerySet[T]
+}
+
+// ValuesList returns the given fields of each result as a slice:
+//
+//	rows, err := qs.ValuesList("id", "title").All() // [][]interface{}
+//	ids, err := qs.ValuesList("id").Flat()          // []interface{}
+func (q *QuerySet[T]) ValuesList(fields ...string) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: q.Values(fields...)}
+}
+
+// Filter adds positive filter criteria
+func (v *ValuesListQuerySet[T]) Filter(nodes ...Node) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.Filter(nodes...)}
+}
+
+// Exclude adds negative filter criteria
+func (v *ValuesListQuerySet[T]) Exclude(nodes ...Node) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.Exclude(nodes...)}
+}
+
+// Annotate adds computed values, which can then be selected by name
+func (v *ValuesListQuerySet[T]) Annotate(annotations map[string]Expression) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.Annotate(annotations)}
+}
+
+// OrderBy sets the sort order
+func (v *ValuesListQuerySet[T]) OrderBy(fields ...string) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.OrderBy(fields...)}
+}
+
+// Limit sets the maximum number of rows
+func (v *ValuesListQuerySet[T]) Limit(n int) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.Limit(n)}
+}
+
+// Offset sets the number of rows to skip
+func (v *ValuesListQuerySet[T]) Offset(n int) *ValuesListQuerySet[T] {
+	return &ValuesListQuerySet[T]{values: v.values.Offset(n)}
+}
+
+// SQL returns the generated SQL query and arguments, without connecting to
+// the database
+func (v *ValuesListQuerySet[T]) SQL() (string, []interface{}, error) {
+	return v.values.SQL()
+}
+
+// All returns the matching rows (Terminal operation)
+func (v *ValuesListQuerySet[T]) All() ([][]interface{}, error) {
+	if len(v.values.fields) == 0 {
+		return nil, fmt.Errorf("ValuesList requires at least one field")
+	}
+	rows, err := v.values.All()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		results[i] = make([]interface{}, len(v.values.fields))
+		for j, field := range v.values.fields {
+			results[i][j] = row[field]
+		}
+	}
+	return results, nil
+}
+
+// Flat returns the single selected field of each row (Terminal operation)
+func (v *ValuesListQuerySet[T]) Flat() ([]interface{}, error) {
+	if len(v.values.fields) != 1 {
+		return nil, fmt.Errorf("Flat requires exactly one field, got %d", len(v.values.fields))
+	}
+	rows, err := v.All()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(rows))
+	for i, row := range rows {
+		results[i] = row[0]
+	}
+	return results, nil
+}
+
+// Count returns the number of rows
+func (v *ValuesListQuerySet[T]) Count() (int, error) {
+	return v.values.Count()
+}
@@ -0,0 +1,72 @@
+package queryset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOnlyDeferSQL(t *testing.T) {
+	qs := &QuerySet[*liteArticle]{}
+
+	tests := []struct {
+		name     string
+		qs       *QuerySet[*liteArticle]
+		expected string
+	}{
+		{"only", qs.Only("title"), "SELECT lite_articles.id, lite_articles.title FROM lite_articles"},
+		{"defer", qs.Defer("created_at").Defer("Views"), "SELECT lite_articles.id, lite_articles.title, lite_articles.published FROM lite_articles"},
+		{"only replaces defer", qs.Defer("title").Only("title", "views"), "SELECT lite_articles.id, lite_articles.title, lite_articles.views FROM lite_articles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql, _, _ := tt.qs.SQL(); sql != tt.expected {
+				t.Errorf("got %q, want %q", sql, tt.expected)
+			}
+		})
+	}
+
+	if _, err := qs.Only("missing").All(); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestValues(t *testing.T) {
+	database := newLibraryDB(t)
+	books := NewQuerySet[*liteBook](database)
+
+	only, err := books.Only("title").OrderBy("id").All()
+	if err != nil {
+		t.Fatalf("Only failed: %v", err)
+	}
+	if len(only) != 3 || only[0].Title != "Go" || only[0].ID != 1 || only[0].Pages != 0 {
+		t.Errorf("unexpected Only results: %+v", only[0])
+	}
+
+	rows, err := books.Filter(Q{"pages__gte": 200}).Values("title", "pages").OrderBy("title").All()
+	if err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	if fmt.Sprint(rows) != "[map[pages:200 title:Rust] map[pages:300 title:SQL]]" {
+		t.Errorf("unexpected values: %v", rows)
+	}
+
+	list, err := books.ValuesList("title", "pages").OrderBy("-pages").Limit(2).All()
+	if err != nil {
+		t.Fatalf("ValuesList failed: %v", err)
+	}
+	if fmt.Sprint(list) != "[[SQL 300] [Rust 200]]" {
+		t.Errorf("unexpected values list: %v", list)
+	}
+
+	ids, err := books.ValuesList("id").OrderBy("id").Flat()
+	if err != nil || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("unexpected flat list: %v (%v)", ids, err)
+	}
+	if _, err := books.ValuesList("id", "title").Flat(); err == nil {
+		t.Error("expected Flat to require a single field")
+	}
+
+	if n, err := books.Values("author_id").Annotate(map[string]Expression{"n": Count("*")}).Count(); err != nil || n != 2 {
+		t.Errorf("expected 2 author groups, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,92 @@
+package queryset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WindowExpression evaluates a window function, or an aggregate, over the
+// rows sharing a partition, in the window's ordering. Window annotations
+// can be used in OrderBy and Filter; a filter on one is applied to the
+// computed rows, e.g. to keep the latest row per group.
+type WindowExpression struct {
+	Expression  Expression
+	PartitionBy []string
+	OrderBy     []string
+}
+
+// Window computes expr over partitions of the rows. partitionBy and orderBy
+// are field paths; orderBy fields starting with "-" sort descending.
+//
+//	latest := books.Annotate(map[string]Expression{
+//		"rank": Window(RowNumber(), []string{"author_id"}, []string{"-published_at"}),
+//	}).Filter(Q{"rank": 1})
+//	running := books.Annotate(map[string]Expression{"total": Window(Sum("pages"), nil, []string{"id"})})
+func Window(expr Expression, partitionBy, orderBy []string) *WindowExpression {
+	return &WindowExpression{Expression: expr, PartitionBy: partitionBy, OrderBy: orderBy}
+}
+
+func (w *WindowExpression) sql(c *compiler) string {
+	var over []string
+	if len(w.PartitionBy) > 0 {
+		cols := make([]string, len(w.PartitionBy))
+		for i, field := range w.PartitionBy {
+			cols[i] = c.ref(field)
+		}
+		over = append(over, "PARTITION BY "+strings.Join(cols, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		cols := make([]string, len(w.OrderBy))
+		for i, field := range w.OrderBy {
+			cols[i] = c.ref(strings.TrimPrefix(field, "-"))
+			if strings.HasPrefix(field, "-") {
+				cols[i] += " DESC"
+			}
+		}
+		over = append(over, "ORDER BY "+strings.Join(cols, ", "))
+	}
+	return fmt.Sprintf("%s OVER (%s)", w.Expression.sql(c), strings.Join(over, " "))
+}
+
+// RowNumber numbers the rows of each partition from 1
+func RowNumber() *Func { return &Func{Function: "ROW_NUMBER"} }
+
+// Rank ranks the rows of each partition, leaving gaps after ties
+func Rank() *Func { return &Func{Function: "RANK"} }
+
+// DenseRank ranks the rows of each partition without gaps after ties
+func DenseRank() *Func { return &Func{Function: "DENSE_RANK"} }
+
+// Lag returns field of the row offset rows before the current one in the
+// partition, or NULL
+func Lag(field string, offset int) *Func {
+	return &Func{Function: "LAG", Args: []interface{}{field, rawSQL(fmt.Sprint(offset))}}
+}
+
+// Lead returns field of the row offset rows after the current one in the
+// partition, or NULL
+func Lead(field string, offset int) *Func {
+	return &Func{Function: "LEAD", Args: []interface{}{field, rawSQL(fmt.Sprint(offset))}}
+}
+
+// windowed reports whether any annotation is computed over a window, whose
+// values depend on every row the query matches
+func (q *QuerySet[T]) windowed() bool {
+	for _, a := range q.annotations {
+		if containsWindow(a.expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWindow reports whether v has a window expression, which can't
+// appear in WHERE or GROUP BY
+func containsWindow(v interface{}) bool {
+	switch v := v.(type) {
+	case *WindowExpression:
+		return true
+	default:
+		return anyOperand(v, containsWindow)
+	}
+}